@@ -0,0 +1,70 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// testRootCmd is the root of the commands under test, the root command of main
+var testRootCmd = commands.NewRootCmd()
+
+// executeCommand executes the command line in an isolated environment and returns the output
+func executeCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("ESSIF_PASSPHRASE", testPassphrase)
+	resetFlags(testRootCmd)
+	output := &bytes.Buffer{}
+	testRootCmd.SetOut(output)
+	testRootCmd.SetArgs(args)
+	err := testRootCmd.Execute()
+	return output.String(), err
+}
+
+// executeJson executes the command line with json output and decodes the result
+func executeJson(t *testing.T, result interface{}, args ...string) error {
+	t.Helper()
+	output, err := executeCommand(t, append(args, "--output", "json")...)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(output), result)
+}
+
+// createDid creates a did in the wallet of the flags with the create arguments and returns the did
+func createDid(t *testing.T, flags []string, args ...string) string {
+	t.Helper()
+	created := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &created, append(append([]string{"create"}, args...), flags...)...))
+	return created["did"].(string)
+}
+
+// resetFlags resets the flags of the command and its sub commands, the flags keep their values between executions
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			defaults := []string{}
+			if value := strings.Trim(flag.DefValue, "[]"); value != "" {
+				defaults = strings.Split(value, ",")
+			}
+			sliceValue.Replace(defaults)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+	for _, subCmd := range cmd.Commands() {
+		resetFlags(subCmd)
+	}
+}
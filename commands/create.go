@@ -7,10 +7,16 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/gossif/admin/did"
 	"github.com/gossif/admin/wallet"
 	"github.com/gossif/ebsi"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -19,42 +25,301 @@ import (
 
 var CreateCmd = &cobra.Command{
 	Use:   "create",
-	Short: "Step 1: Create an ebsi decentralized identifier.",
-	Run: func(_ *cobra.Command, _ []string) {
-		did := ebsi.NewDecentralizedIdentifier()
-		did.GenerateMethodSpecificId()
-
-		jwkIssuanceKey, _ := generateSecp256r1AsJwk(did.String())
-		jwkPublicKey, _ := jwkIssuanceKey.PublicKey()
-		didDocument := map[string]interface{}{
-			"@context": []string{"https://www.w3.org/ns/did/v1"},
-			"id":       did.String(),
-			"verificationMethod": []map[string]interface{}{
-				{
-					"id":           jwkPublicKey.KeyID(),
-					"type":         "JsonWebKey2020",
-					"controller":   did.String(),
-					"publicKeyJwk": jwkPublicKey,
-				},
-			},
-			"authentication":  []string{jwkPublicKey.KeyID()},
-			"assertionMethod": []string{jwkPublicKey.KeyID()},
+	Short: "Step 1: Create a decentralized identifier (ebsi, key or web).",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		method, _ := cmd.Flags().GetString("method")
+		keyType, _ := cmd.Flags().GetString("key-type")
+		domain, _ := cmd.Flags().GetString("domain")
+		subject, _ := cmd.Flags().GetString("subject")
+		var (
+			didBucket wallet.DidBucket
+			err       error
+		)
+		switch method {
+		case "", "ebsi":
+			switch subject {
+			case "", "legal":
+				didBucket, err = newEbsiBucket()
+			case "natural":
+				didBucket, err = newEbsiNaturalPersonBucket(keyType)
+			default:
+				err = &UsageError{Err: fmt.Errorf("unsupported subject: %s", subject)}
+			}
+		case "key":
+			didBucket, err = newKeyBucket(keyType)
+		case "web":
+			didBucket, err = newWebBucket(domain, keyType)
+		default:
+			err = &UsageError{Err: fmt.Errorf("unsupported method: %s", method)}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create the did: %w", err)
+		}
+		if err := didBucket.ChangeState(wallet.StateChange{State: wallet.StateCreated}); err != nil {
+			return err
 		}
-		jwkPresentationKey, _ := generateSecp256r1AsJwk(did.String())
-		didBucket := wallet.DidBucket{
-			Did:             did.String(),
-			Document:        didDocument,
-			IssuanceKey:     jwkIssuanceKey,
-			PresentationKey: jwkPresentationKey,
+		created := createResult{
+			Did:      didBucket.Did,
+			Method:   didMethod(didBucket.Did),
+			State:    didBucket.State,
+			Keys:     bucketKeys(didBucket),
+			Document: didBucket.Document,
+		}
+		// the did document is written first, a did:web isn't saved without its document
+		if did.IsWeb(didBucket.Did) {
+			outDir, _ := cmd.Flags().GetString("out")
+			documentFile, err := writeWebDocument(outDir, didBucket)
+			if err != nil {
+				return fmt.Errorf("failed to write the did document: %w", err)
+			}
+			created.DocumentFile = documentFile
+			created.DocumentUrl, _ = did.WebDocumentUrl(didBucket.Did)
 		}
 		if err := wallet.StoreBucket(didBucket); err != nil {
-			fmt.Printf("Failed to save the results\n'%s'\n", err)
-			return
+			return fmt.Errorf("failed to save the results: %w", err)
 		}
-		fmt.Printf("Creating of did %s succeeded\n", did.String())
+		return printResult(cmd, created)
 	},
 }
 
+func init() {
+	CreateCmd.Flags().StringP("method", "m", "ebsi", "the method used to create the did (ebsi, key or web).")
+	CreateCmd.Flags().StringP("key-type", "k", "P-256", "the key type of the key and web method (P-256 or secp256k1).")
+	CreateCmd.Flags().StringP("domain", "d", "", "the domain for the web method, f.e. example.org or example.org:user:alice.")
+	CreateCmd.Flags().StringP("subject", "s", "legal", "the subject of the ebsi method (legal or natural).")
+	CreateCmd.Flags().StringP("out", "o", ".", "the directory to write the did document of the web method to.")
+}
+
+// createResult is the result of the create command
+type createResult struct {
+	Did          string                 `json:"did"`
+	Method       string                 `json:"method"`
+	State        string                 `json:"state"`
+	Keys         []keySummary           `json:"keys"`
+	Document     map[string]interface{} `json:"document"`
+	DocumentFile string                 `json:"documentFile,omitempty"`
+	DocumentUrl  string                 `json:"documentUrl,omitempty"`
+}
+
+func (r createResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Creating of did %s succeeded\n", r.Did)
+	if r.DocumentFile != "" {
+		fmt.Fprintf(w, "The did document is written to %s, publish it at %s\n", r.DocumentFile, r.DocumentUrl)
+	}
+}
+
+func (r createResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, key := range r.Keys {
+		rows = append(rows, []string{r.Did, key.Id, key.KeyType, strings.Join(key.Purposes, ",")})
+	}
+	return []string{"did", "key id", "key type", "purposes"}, rows
+}
+
+// keySummary describes a key of the did bucket without the key material
+type keySummary struct {
+	Id       string   `json:"id"`
+	KeyType  string   `json:"keyType"`
+	Purposes []string `json:"purposes"`
+}
+
+// bucketKeys returns the summaries of the issuance, presentation and added keys, a key used for both issuance
+// and presentation is listed once
+func bucketKeys(didBucket wallet.DidBucket) []keySummary {
+	keys := []keySummary{}
+	for _, bucketKey := range []struct {
+		purpose string
+		key     jwk.Key
+	}{
+		{purpose: "issuance", key: didBucket.IssuanceKey},
+		{purpose: "presentation", key: didBucket.PresentationKey},
+	} {
+		if bucketKey.key == nil {
+			continue
+		}
+		if n := len(keys); n > 0 && keys[n-1].Id == bucketKey.key.KeyID() {
+			keys[n-1].Purposes = append(keys[n-1].Purposes, bucketKey.purpose)
+			continue
+		}
+		keys = append(keys, keySummary{Id: bucketKey.key.KeyID(), KeyType: keyCurve(bucketKey.key), Purposes: []string{bucketKey.purpose}})
+	}
+	for _, verificationKey := range didBucket.VerificationKeys {
+		keys = append(keys, keySummary{Id: verificationKey.Key.KeyID(), KeyType: keyCurve(verificationKey.Key), Purposes: verificationKey.Purposes})
+	}
+	return keys
+}
+
+// keyCurve returns the curve of the elliptic curve key
+func keyCurve(jwkKey jwk.Key) string {
+	if crv, ok := jwkKey.Get(jwk.ECDSACrvKey); ok {
+		return fmt.Sprint(crv)
+	}
+	return ""
+}
+
+// newEbsiBucket creates an ebsi identifier for a legal entity with an issuance and presentation key. The
+// presentation key is an authentication method of the did document, to verify the presentations of the did.
+func newEbsiBucket() (wallet.DidBucket, error) {
+	did := ebsi.NewDecentralizedIdentifier()
+	did.GenerateMethodSpecificId()
+
+	jwkIssuanceKey, err := generateSecp256r1AsJwk(did.String())
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkPublicKey, _ := jwkIssuanceKey.PublicKey()
+	jwkPresentationKey, err := generateSecp256r1AsJwk(did.String())
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkPresentationPublicKey, _ := jwkPresentationKey.PublicKey()
+	didDocument := map[string]interface{}{
+		"@context": []string{"https://www.w3.org/ns/did/v1"},
+		"id":       did.String(),
+		"verificationMethod": []map[string]interface{}{
+			{
+				"id":           jwkPublicKey.KeyID(),
+				"type":         "JsonWebKey2020",
+				"controller":   did.String(),
+				"publicKeyJwk": jwkPublicKey,
+			},
+			{
+				"id":           jwkPresentationPublicKey.KeyID(),
+				"type":         "JsonWebKey2020",
+				"controller":   did.String(),
+				"publicKeyJwk": jwkPresentationPublicKey,
+			},
+		},
+		"authentication":  []string{jwkPublicKey.KeyID(), jwkPresentationPublicKey.KeyID()},
+		"assertionMethod": []string{jwkPublicKey.KeyID()},
+	}
+	return wallet.DidBucket{
+		Did:             did.String(),
+		Document:        didDocument,
+		IssuanceKey:     jwkIssuanceKey,
+		PresentationKey: jwkPresentationKey,
+	}, nil
+}
+
+// newEbsiNaturalPersonBucket creates an ebsi identifier for a natural person, derived from the holder key.
+// The key is used for both issuance and presentation.
+func newEbsiNaturalPersonBucket(keyType string) (wallet.DidBucket, error) {
+	jwkKey, err := generateKeyAsJwk(keyType, "")
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkPublicKey, err := jwkKey.PublicKey()
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	identifier, err := did.NewEbsiNaturalPerson(jwkPublicKey)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	kid, err := did.EbsiNaturalPersonKeyId(identifier, jwkPublicKey)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkKey.Set(jwk.KeyIDKey, kid)
+	jwkPublicKey.Set(jwk.KeyIDKey, kid)
+	return wallet.DidBucket{
+		Did:             identifier,
+		Document:        did.NewDocument(identifier, jwkPublicKey),
+		IssuanceKey:     jwkKey,
+		PresentationKey: jwkKey,
+	}, nil
+}
+
+// newKeyBucket creates a did:key identifier, the key is used for both issuance and presentation
+func newKeyBucket(keyType string) (wallet.DidBucket, error) {
+	jwkKey, err := generateKeyAsJwk(keyType, "")
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkPublicKey, err := jwkKey.PublicKey()
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	identifier, err := did.NewKey(jwkPublicKey)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkKey.Set(jwk.KeyIDKey, did.KeyVerificationMethodId(identifier))
+	didDocument, err := did.ResolveKey(identifier)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	return wallet.DidBucket{
+		Did:             identifier,
+		Document:        didDocument,
+		IssuanceKey:     jwkKey,
+		PresentationKey: jwkKey,
+	}, nil
+}
+
+// newWebBucket creates a did:web identifier for the domain with an issuance and presentation key
+func newWebBucket(domain string, keyType string) (wallet.DidBucket, error) {
+	if strings.TrimSpace(domain) == "" {
+		return wallet.DidBucket{}, &UsageError{Err: errors.New("the domain is required for the web method")}
+	}
+	identifier, err := did.NewWeb(domain)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkIssuanceKey, err := generateKeyAsJwk(keyType, identifier)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkPresentationKey, err := generateKeyAsJwk(keyType, identifier)
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	jwkIssuancePublicKey, _ := jwkIssuanceKey.PublicKey()
+	jwkPresentationPublicKey, _ := jwkPresentationKey.PublicKey()
+	return wallet.DidBucket{
+		Did:             identifier,
+		Document:        did.NewDocument(identifier, jwkIssuancePublicKey, jwkPresentationPublicKey),
+		IssuanceKey:     jwkIssuanceKey,
+		PresentationKey: jwkPresentationKey,
+	}, nil
+}
+
+// writeWebDocument writes the did document to the path of the did:web identifier in the directory
+func writeWebDocument(outDir string, didBucket wallet.DidBucket) (string, error) {
+	documentPath, err := did.WebDocumentPath(didBucket.Did)
+	if err != nil {
+		return "", err
+	}
+	documentFile := filepath.Join(outDir, filepath.FromSlash(documentPath))
+	if err = os.MkdirAll(filepath.Dir(documentFile), 0755); err != nil {
+		return "", err
+	}
+	documentBytes, err := json.MarshalIndent(didBucket.Document, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return documentFile, os.WriteFile(documentFile, documentBytes, 0644)
+}
+
+// generateKeyAsJwk generates a key pair of the key type and returns private key as json web key
+func generateKeyAsJwk(keyType string, didController string) (jwk.Key, error) {
+	switch keyType {
+	case did.KeyTypeP256:
+		return generateSecp256r1AsJwk(didController)
+	case did.KeyTypeSecp256k1:
+		return generateSecp256k1AsJwk(didController)
+	default:
+		return nil, &UsageError{Err: fmt.Errorf("unsupported key type: %s", keyType)}
+	}
+}
+
 // generateSecp256r1AsJwk generates secp256r1 key pair and returns private key as json web key
 func generateSecp256r1AsJwk(didController string) (jwk.Key, error) {
 	rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
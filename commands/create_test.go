@@ -0,0 +1,40 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWeb(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+
+	t.Run("document", func(t *testing.T) {
+		outDir := t.TempDir()
+		created := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &created, append([]string{"create", "--method", "web", "--domain", "example.org", "--out", outDir}, flags...)...))
+		assert.Equal(t, filepath.Join(outDir, ".well-known", "did.json"), created["documentFile"])
+		assert.FileExists(t, filepath.Join(outDir, ".well-known", "did.json"))
+	})
+	t.Run("unwritable document", func(t *testing.T) {
+		outFile := filepath.Join(t.TempDir(), "not-a-directory")
+		require.NoError(t, os.WriteFile(outFile, nil, 0600))
+		_, err := executeCommand(t, append([]string{"create", "--method", "web", "--domain", "example.com", "--out", outFile}, flags...)...)
+		assert.Error(t, err)
+
+		output, err := executeCommand(t, append([]string{"list"}, flags...)...)
+		require.NoError(t, err)
+		listed := []map[string]string{}
+		require.NoError(t, json.Unmarshal([]byte(output), &listed))
+		for _, entry := range listed {
+			assert.NotEqual(t, "did:web:example.com", entry["did"], "the did without its document isn't saved")
+		}
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var errDeactivationCancelled = errors.New("the deactivation is cancelled")
+
+var DeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate the did, the keys of a deactivated did are no longer used for signing.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		confirmed, _ := cmd.Flags().GetBool("yes")
+		if !confirmed && !isTerminal() {
+			return &UsageError{Err: errors.New("the deactivation can't be undone, confirm it with --yes")}
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if err = wallet.CanChangeState(didBucket.State, wallet.StateDeactivated); err != nil {
+			return err
+		}
+		onLedger := isActiveOnLedger(didBucket)
+		if !confirmed {
+			if !confirmDestructivePrompt(fmt.Sprintf("Deactivate the did %s? The deactivation can't be undone.", did)) {
+				return errDeactivationCancelled
+			}
+			if onLedger && stringPrompt("Type the did to confirm the deactivation in the did registry.") != did {
+				return errDeactivationCancelled
+			}
+		}
+		deactivated := deactivateResult{Did: did, OnLedger: onLedger}
+		if onLedger {
+			if deactivated.TransactionHash, err = deactivateOnLedger(env, &didBucket); err != nil {
+				return err
+			}
+		}
+		if err = didBucket.ChangeState(wallet.StateChange{State: wallet.StateDeactivated, TransactionHash: deactivated.TransactionHash}); err != nil {
+			return err
+		}
+		didBucket.AccessToken, didBucket.AccessTokenScope = "", ""
+		if err = wallet.StoreBucket(didBucket); err != nil {
+			return fmt.Errorf("failed to save the results: %w", err)
+		}
+		deactivated.State = didBucket.State
+		deactivated.DocumentUrl, _ = didkit.WebDocumentUrl(did)
+		return printResult(cmd, deactivated)
+	},
+}
+
+func init() {
+	DeactivateCmd.Flags().StringP("did", "d", "", "the did to deactivate.")
+	DeactivateCmd.Flags().Bool("yes", false, "deactivate without the confirmation prompts.")
+}
+
+// deactivateResult is the result of the deactivate command
+type deactivateResult struct {
+	Did             string `json:"did"`
+	State           string `json:"state"`
+	OnLedger        bool   `json:"onLedger"`
+	TransactionHash string `json:"transactionHash,omitempty"`
+	DocumentUrl     string `json:"documentUrl,omitempty"`
+}
+
+func (r deactivateResult) writeText(w io.Writer) {
+	switch {
+	case r.OnLedger:
+		fmt.Fprintf(w, "Deactivating of did %s in the did registry succeeded\n", r.Did)
+	case r.DocumentUrl != "":
+		fmt.Fprintf(w, "The did %s is deactivated in the wallet, remove the did document from %s\n", r.Did, r.DocumentUrl)
+	default:
+		fmt.Fprintf(w, "The did %s is deactivated in the wallet\n", r.Did)
+	}
+}
+
+func (r deactivateResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "state", "on ledger", "transaction hash"},
+		[][]string{{r.Did, r.State, fmt.Sprint(r.OnLedger), r.TransactionHash}}
+}
+
+// deactivateOnLedger sets the metadata of the registered did document to deactivated, the transaction is signed
+// with the transaction key of the bucket. It returns the transaction hash.
+func deactivateOnLedger(env config.Environment, didBucket *wallet.DidBucket) (string, error) {
+	if didBucket.AdminTransactionKey == nil {
+		return "", fmt.Errorf("the did %s has no transaction key", didBucket.Did)
+	}
+	if err := ledgerAccessToken(env, didBucket); err != nil {
+		return "", err
+	}
+	transactionHash, err := newEbsiClient(env).UpdateDidDocument(
+		didBucket.AccessToken,
+		didBucket.Did,
+		didBucket.Document,
+		map[string]interface{}{"deactivated": true},
+		didBucket.AdminTransactionKey,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to deactivate the did document: %w", remoteError(err))
+	}
+	return transactionHash, nil
+}
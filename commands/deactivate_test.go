@@ -0,0 +1,40 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeactivateWeb(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+
+	did := createDid(t, flags, "--method", "web", "--domain", "example.org", "--out", t.TempDir())
+
+	t.Run("not confirmed", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"deactivate", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("confirmed", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"deactivate", "--did", did, "--yes"}, flags...)...)
+		require.NoError(t, err)
+		deactivated := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &deactivated))
+		assert.Equal(t, "deactivated", deactivated["state"])
+		assert.Equal(t, false, deactivated["onLedger"])
+		assert.Equal(t, "https://example.org/.well-known/did.json", deactivated["documentUrl"])
+	})
+	t.Run("status", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"status", "--did", did}, flags...)...)
+		require.NoError(t, err)
+		status := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &status))
+		assert.Equal(t, "deactivated", status["state"])
+	})
+}
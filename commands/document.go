@@ -0,0 +1,292 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/cobra"
+)
+
+// verificationRelationships are the purposes of the keys added to the did document
+var verificationRelationships = []string{"authentication", "assertionMethod", "capabilityInvocation"}
+
+var DocumentCmd = &cobra.Command{
+	Use:   "document",
+	Short: "Manage the verification methods of a registered did document (ebsi only).",
+}
+
+var DocumentAddKeyCmd = &cobra.Command{
+	Use:   "add-key",
+	Short: "Add a new key as verification method to the registered did document.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := ebsiDidFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if didkit.IsEbsiNaturalPerson(did) {
+			return &InvalidDidError{Did: did, Err: errNaturalPerson}
+		}
+		keyType, _ := cmd.Flags().GetString("type")
+		if keyType != didkit.KeyTypeP256 && keyType != didkit.KeyTypeSecp256k1 {
+			return &UsageError{Err: fmt.Errorf("unsupported key type: %s", keyType)}
+		}
+		purposes, _ := cmd.Flags().GetStringSlice("purpose")
+		if err = validatePurposes(purposes); err != nil {
+			return err
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if err = wallet.CanChangeState(didBucket.State, wallet.StateUpdated); err != nil {
+			return err
+		}
+		jwkKey, err := generateKeyAsJwk(keyType, did)
+		if err != nil {
+			return err
+		}
+		result, addErr := publishBucketKey(env, &didBucket, jwkKey, purposes)
+		if result.TransactionHashes == nil {
+			return addErr
+		}
+		// the key is on the ledger, the key is saved even when a relationship failed
+		didBucket.VerificationKeys = append(didBucket.VerificationKeys, wallet.VerificationKey{
+			Key:       jwkKey,
+			Purposes:  result.Purposes,
+			ValidFrom: time.Now().UTC(),
+		})
+		if err = didBucket.ChangeState(wallet.StateChange{
+			State:           wallet.StateUpdated,
+			TransactionHash: result.TransactionHashes[len(result.TransactionHashes)-1],
+		}); err != nil {
+			return err
+		}
+		result.State = didBucket.State
+		if err = wallet.StoreBucket(didBucket); err != nil {
+			return fmt.Errorf("failed to save the results: %w", err)
+		}
+		if addErr != nil {
+			return addErr
+		}
+		return printResult(cmd, result)
+	},
+}
+
+// documentKeyResult is the result of the document add-key command
+type documentKeyResult struct {
+	Did               string   `json:"did"`
+	KeyId             string   `json:"keyId"`
+	KeyType           string   `json:"keyType"`
+	Purposes          []string `json:"purposes"`
+	State             string   `json:"state"`
+	TransactionHashes []string `json:"transactionHashes"`
+}
+
+func (r documentKeyResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Adding the key %s to the did document of %s succeeded (%s)\n", r.KeyId, r.Did, strings.Join(r.Purposes, ", "))
+}
+
+func (r documentKeyResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "key id", "key type", "purposes", "state"},
+		[][]string{{r.Did, r.KeyId, r.KeyType, strings.Join(r.Purposes, ","), r.State}}
+}
+
+// validatePurposes returns a usage error when a purpose is not a supported verification relationship
+func validatePurposes(purposes []string) error {
+	if len(purposes) == 0 {
+		return &UsageError{Err: fmt.Errorf("the purpose is required (%s)", strings.Join(verificationRelationships, ", "))}
+	}
+	for _, purpose := range purposes {
+		supported := false
+		for _, relationship := range verificationRelationships {
+			supported = supported || purpose == relationship
+		}
+		if !supported {
+			return &UsageError{Err: fmt.Errorf("unsupported purpose: %s", purpose)}
+		}
+	}
+	return nil
+}
+
+// publishBucketKey adds the key as verification method to the registered did document with the transaction key
+// of the bucket and to the document of the bucket. The transaction hashes of the result are nil when nothing is
+// changed on the ledger.
+func publishBucketKey(env config.Environment, didBucket *wallet.DidBucket, jwkKey jwk.Key, purposes []string) (documentKeyResult, error) {
+	result := documentKeyResult{Did: didBucket.Did, KeyId: jwkKey.KeyID(), KeyType: keyCurve(jwkKey), Purposes: []string{}}
+	if didBucket.AdminTransactionKey == nil {
+		return result, fmt.Errorf("the did %s has no transaction key, register the did first", didBucket.Did)
+	}
+	if err := ledgerAccessToken(env, didBucket); err != nil {
+		return result, err
+	}
+	documentKey, err := documentPublicKey(jwkKey)
+	if err != nil {
+		return result, err
+	}
+	client := newEbsiClient(env)
+	transactionHash, err := client.AddVerificationMethod(didBucket.AccessToken, didBucket.Did, jwkKey.KeyID(), documentKey, didBucket.AdminTransactionKey)
+	if err != nil {
+		return result, fmt.Errorf("failed to add the verification method: %w", remoteError(err))
+	}
+	result.TransactionHashes = []string{transactionHash}
+	for _, purpose := range purposes {
+		transactionHash, err = client.AddVerificationRelationship(didBucket.AccessToken, didBucket.Did, purpose, jwkKey.KeyID(), time.Time{}, didBucket.AdminTransactionKey)
+		if err != nil {
+			err = fmt.Errorf("failed to add the %s relationship: %w", purpose, remoteError(err))
+			break
+		}
+		result.TransactionHashes = append(result.TransactionHashes, transactionHash)
+		result.Purposes = append(result.Purposes, purpose)
+	}
+	didkit.AddVerificationMethod(didBucket.Document, jwkKey.KeyID(), documentKey, result.Purposes...)
+	return result, err
+}
+
+// documentPublicKey returns the public key as published in the did document, the key id is the id of
+// the verification method
+func documentPublicKey(jwkKey jwk.Key) (jwk.Key, error) {
+	publicKey, err := jwkKey.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	publicKey.Remove(jwk.KeyIDKey)
+	return publicKey, nil
+}
+
+var DocumentKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "List the keys of the did with the retired keys of the key history.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		keys := documentKeysResult{Did: did, Keys: []keyValidity{}}
+		for _, bucketKey := range []struct {
+			role string
+			key  jwk.Key
+		}{
+			{role: wallet.KeyRoleIssuance, key: didBucket.IssuanceKey},
+			{role: wallet.KeyRolePresentation, key: didBucket.PresentationKey},
+		} {
+			if bucketKey.key == nil {
+				continue
+			}
+			if n := len(keys.Keys); n > 0 && keys.Keys[n-1].Id == bucketKey.key.KeyID() {
+				keys.Keys[n-1].Role += "," + bucketKey.role
+				continue
+			}
+			keys.Keys = append(keys.Keys, newKeyValidity(didBucket, wallet.VerificationKey{Key: bucketKey.key, Role: bucketKey.role}))
+		}
+		for _, verificationKey := range didBucket.VerificationKeys {
+			keys.Keys = append(keys.Keys, newKeyValidity(didBucket, verificationKey))
+		}
+		for _, retiredKey := range didBucket.KeyHistory {
+			keys.Keys = append(keys.Keys, newKeyValidity(didBucket, retiredKey))
+		}
+		return printResult(cmd, keys)
+	},
+}
+
+func init() {
+	DocumentCmd.AddCommand(DocumentAddKeyCmd)
+	DocumentCmd.AddCommand(DocumentRotateKeyCmd)
+	DocumentCmd.AddCommand(DocumentKeysCmd)
+	DocumentAddKeyCmd.Flags().StringP("did", "d", "", "the did of the document to add the key to.")
+	DocumentAddKeyCmd.Flags().StringSlice("purpose", []string{"authentication"}, "the verification relationships of the key (authentication, assertionMethod or capabilityInvocation).")
+	DocumentAddKeyCmd.Flags().String("type", "P-256", "the key type of the key (P-256 or secp256k1).")
+	DocumentKeysCmd.Flags().StringP("did", "d", "", "the did of the keys to list.")
+}
+
+// keyValidity is the summary of a key with its validity, a retired key is valid until it is rotated
+type keyValidity struct {
+	Id         string     `json:"id"`
+	KeyType    string     `json:"keyType"`
+	Role       string     `json:"role,omitempty"`
+	Purposes   []string   `json:"purposes"`
+	ValidFrom  *time.Time `json:"validFrom,omitempty"`
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+}
+
+// newKeyValidity returns the validity of the key, the purposes of a current key are the relationships of
+// the did document
+func newKeyValidity(didBucket wallet.DidBucket, key wallet.VerificationKey) keyValidity {
+	validity := keyValidity{Id: key.Key.KeyID(), KeyType: keyCurve(key.Key), Role: key.Role, Purposes: key.Purposes}
+	if key.ValidUntil.IsZero() {
+		validity.Purposes = didkit.VerificationRelationships(didBucket.Document, key.Key.KeyID())
+		key.ValidFrom = didBucket.KeyValidFrom(key.Key.KeyID(), key.Role)
+	} else {
+		validity.ValidUntil = &key.ValidUntil
+	}
+	if !key.ValidFrom.IsZero() {
+		validity.ValidFrom = &key.ValidFrom
+	}
+	if validity.Purposes == nil {
+		validity.Purposes = []string{}
+	}
+	return validity
+}
+
+// documentKeysResult is the result of the document keys command
+type documentKeysResult struct {
+	Did  string        `json:"did"`
+	Keys []keyValidity `json:"keys"`
+}
+
+func (r documentKeysResult) writeText(w io.Writer) {
+	for _, key := range r.Keys {
+		if key.ValidUntil == nil {
+			fmt.Fprintf(w, "%s (%s)\n", key.Id, strings.Join(key.Purposes, ", "))
+			continue
+		}
+		fmt.Fprintf(w, "%s (%s) retired at %s\n", key.Id, strings.Join(key.Purposes, ", "), key.ValidUntil.Local().Format(time.RFC3339))
+	}
+}
+
+func (r documentKeysResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, key := range r.Keys {
+		validFrom, validUntil := "", ""
+		if key.ValidFrom != nil {
+			validFrom = key.ValidFrom.Local().Format(time.RFC3339)
+		}
+		if key.ValidUntil != nil {
+			validUntil = key.ValidUntil.Local().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{key.Id, key.KeyType, key.Role, strings.Join(key.Purposes, ","), validFrom, validUntil})
+	}
+	return []string{"key id", "key type", "role", "purposes", "valid from", "valid until"}, rows
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build jwx_es256k
+
+package commands_test
+
+import (
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDid creates, onboards and registers a did with the mock and returns the did
+func setupDid(t *testing.T, flags []string) string {
+	t.Helper()
+	setup := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--token", "onboarding-token"}, flags...)...))
+	return setup["did"].(string)
+}
+
+func TestDocumentAddKey(t *testing.T) {
+	server, flags := newMockServer(t)
+	did := setupDid(t, flags)
+
+	t.Run("secp256k1", func(t *testing.T) {
+		added := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &added, append([]string{"document", "add-key", "--did", did, "--type", "secp256k1", "--purpose", "authentication,capabilityInvocation"}, flags...)...))
+		assert.Equal(t, "updated", added["state"])
+		assert.Equal(t, "secp256k1", added["keyType"])
+		assert.Len(t, added["transactionHashes"], 3)
+
+		document, _ := server.Document(did)
+		assert.Contains(t, document["authentication"], added["keyId"])
+		assert.Contains(t, document["capabilityInvocation"], added["keyId"])
+		assert.NotContains(t, document["assertionMethod"], added["keyId"])
+	})
+	t.Run("P-256", func(t *testing.T) {
+		added := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &added, append([]string{"document", "add-key", "--did", did, "--purpose", "assertionMethod"}, flags...)...))
+		assert.Equal(t, "P-256", added["keyType"])
+		document, _ := server.Document(did)
+		assert.Contains(t, document["assertionMethod"], added["keyId"])
+	})
+	t.Run("document in wallet", func(t *testing.T) {
+		setup := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--did", did}, flags...)...))
+		assert.Equal(t, true, setup["verified"])
+
+		status := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &status, append([]string{"status", "--did", did}, flags...)...))
+		assert.Equal(t, "updated", status["state"])
+	})
+	t.Run("invalid purpose", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"document", "add-key", "--did", did, "--purpose", "keyAgreement"}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("not registered", func(t *testing.T) {
+		did := createDid(t, flags)
+		_, err := executeCommand(t, append([]string{"document", "add-key", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+	})
+}
+
+func TestDocumentAddKeyLedgerFailure(t *testing.T) {
+	fail, flags := newFailingMockServer(t)
+	did := setupDid(t, flags)
+	accessToken := storedAccessToken(t, flags, did)
+
+	fail.Store(true)
+	_, err := executeCommand(t, append([]string{"document", "add-key", "--did", did}, flags...)...)
+	assert.Equal(t, commands.ExitRemote, commands.ExitCode(err))
+	assert.NotEqual(t, accessToken, storedAccessToken(t, flags, did), "the refreshed access token is saved")
+}
+
+func TestDocumentRotateKey(t *testing.T) {
+	server, flags := newMockServer(t)
+	did := setupDid(t, flags)
+
+	t.Run("presentation", func(t *testing.T) {
+		rotated := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &rotated, append([]string{"document", "rotate-key", "--did", did, "--key", "presentation"}, flags...)...))
+		assert.Equal(t, "updated", rotated["state"])
+		assert.NotEmpty(t, rotated["transactionHashes"])
+
+		document, _ := server.Document(did)
+		assert.Contains(t, document["authentication"], rotated["keyId"])
+		assert.NotContains(t, document["assertionMethod"], rotated["keyId"])
+		assert.NotContains(t, document["authentication"], rotated["retiredKeyId"])
+	})
+	t.Run("issuance", func(t *testing.T) {
+		rotated := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &rotated, append([]string{"document", "rotate-key", "--did", did, "--key", "issuance"}, flags...)...))
+		assert.Equal(t, "updated", rotated["state"])
+		assert.Len(t, rotated["transactionHashes"], 4)
+
+		document, _ := server.Document(did)
+		assert.Contains(t, document["authentication"], rotated["keyId"])
+		assert.Contains(t, document["assertionMethod"], rotated["keyId"])
+		assert.NotContains(t, document["assertionMethod"], rotated["retiredKeyId"])
+	})
+	t.Run("added key", func(t *testing.T) {
+		added := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &added, append([]string{"document", "add-key", "--did", did, "--type", "secp256k1"}, flags...)...))
+		rotated := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &rotated, append([]string{"document", "rotate-key", "--did", did, "--key", added["keyId"].(string)}, flags...)...))
+		assert.Equal(t, "secp256k1", rotated["keyType"])
+		assert.Equal(t, []interface{}{"authentication"}, rotated["purposes"])
+	})
+	t.Run("document in wallet", func(t *testing.T) {
+		setup := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--did", did}, flags...)...))
+		assert.Equal(t, true, setup["verified"])
+
+		keys := struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}{}
+		assert.NoError(t, executeJson(t, &keys, append([]string{"document", "keys", "--did", did}, flags...)...))
+		assert.Len(t, keys.Keys, 6)
+	})
+}
@@ -0,0 +1,342 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build jwx_es256k
+
+package commands_test
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/commands"
+	"github.com/gossif/admin/mock"
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockServer starts the mock of the ebsi apis and returns the flags to use it with a new wallet
+func newMockServer(t *testing.T) (*mock.Server, []string) {
+	t.Helper()
+	server, err := mock.NewServer(mock.WithOnboardingToken("onboarding-token"))
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, []string{"--env", "local", "--base-url", httpServer.URL, "--backend", "dir", "--wallet", t.TempDir()}
+}
+
+// newFailingMockServer starts the mock of the ebsi apis with access tokens that are requested again by every
+// command, the calls of the did registry fail when fail is set. It returns the flags to use it with a new wallet.
+func newFailingMockServer(t *testing.T) (*atomic.Bool, []string) {
+	t.Helper()
+	server, err := mock.NewServer(mock.WithOnboardingToken("onboarding-token"), mock.WithAccessTokenTtl(30*time.Second))
+	require.NoError(t, err)
+	fail := &atomic.Bool{}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() && r.URL.Path == "/did-registry/v3/jsonrpc" {
+			http.Error(w, "the did registry is unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return fail, []string{"--env", "local", "--base-url", httpServer.URL, "--backend", "dir", "--wallet", t.TempDir()}
+}
+
+// storedAccessToken returns the access token of the did in the wallet of the flags
+func storedAccessToken(t *testing.T, flags []string, did string) string {
+	t.Helper()
+	require.NoError(t, wallet.Open(wallet.BackendDirectory, flags[len(flags)-1]))
+	defer wallet.Close()
+	require.NoError(t, wallet.Unlock(testPassphrase))
+	didBucket, err := wallet.GetBucketByDid(did)
+	require.NoError(t, err)
+	return didBucket.AccessToken
+}
+
+func TestEndToEnd(t *testing.T) {
+	server, flags := newMockServer(t)
+
+	did := createDid(t, flags)
+
+	t.Run("onboard", func(t *testing.T) {
+		onboarded := map[string]string{}
+		assert.NoError(t, executeJson(t, &onboarded, append([]string{"onboard", "--did", did, "--token", "onboarding-token"}, flags...)...))
+		assert.Equal(t, "onboarded", onboarded["state"])
+	})
+	t.Run("register", func(t *testing.T) {
+		registered := map[string]string{}
+		assert.NoError(t, executeJson(t, &registered, append([]string{"register", "--did", did}, flags...)...))
+		assert.Equal(t, "registered", registered["state"])
+		document, found := server.Document(did)
+		assert.True(t, found)
+		assert.Equal(t, did, document["id"])
+	})
+	t.Run("status", func(t *testing.T) {
+		status := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &status, append([]string{"status", "--did", did}, flags...)...))
+		assert.Equal(t, "registered", status["state"])
+		assert.Regexp(t, "^0x[0-9a-f]{64}$", status["transactionHash"])
+		assert.Len(t, status["history"], 3)
+	})
+	t.Run("resolve", func(t *testing.T) {
+		resolved := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &resolved, append([]string{"resolve", "--did", did}, flags...)...))
+		assert.Equal(t, did, resolved["document"].(map[string]interface{})["id"])
+	})
+	t.Run("verbose", func(t *testing.T) {
+		// the ebsi package dumps to stdout, the dumps of the http client go to stderr
+		stdout := os.Stdout
+		reader, writer, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stdout = writer
+		resolved := map[string]interface{}{}
+		err = executeJson(t, &resolved, append([]string{"resolve", "--did", did, "--verbose"}, flags...)...)
+		os.Stdout = stdout
+		writer.Close()
+		require.NoError(t, err)
+		printed, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Empty(t, string(printed))
+	})
+	t.Run("token", func(t *testing.T) {
+		accessToken := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &accessToken, append([]string{"token", "--did", did, "--refresh"}, flags...)...))
+		assert.NotEmpty(t, accessToken["accessToken"])
+		assert.Equal(t, false, accessToken["cached"])
+
+		cachedToken := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &cachedToken, append([]string{"token", "--did", did}, flags...)...))
+		assert.Equal(t, accessToken["accessToken"], cachedToken["accessToken"])
+		assert.Equal(t, true, cachedToken["cached"])
+	})
+	t.Run("list", func(t *testing.T) {
+		listed := []map[string]string{}
+		assert.NoError(t, executeJson(t, &listed, append([]string{"list"}, flags...)...))
+		assert.Equal(t, []map[string]string{{"did": did, "method": "ebsi", "state": "registered"}}, listed)
+	})
+}
+
+func TestEndToEndFailures(t *testing.T) {
+	_, flags := newMockServer(t)
+
+	did := createDid(t, flags)
+
+	t.Run("invalid did", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"onboard", "--did", "did:example:123", "--token", "onboarding-token"}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidDid, commands.ExitCode(err))
+	})
+	t.Run("unknown did", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"onboard", "--did", "did:ebsi:zfEmvX5twhXjQJiCWsukvQA", "--token", "onboarding-token"}, flags...)...)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+	t.Run("invalid onboarding token", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"onboard", "--did", did, "--token", "invalid-token"}, flags...)...)
+		assert.Equal(t, commands.ExitRemote, commands.ExitCode(err))
+		remoteError := &commands.RemoteError{}
+		assert.ErrorAs(t, err, &remoteError)
+		assert.Equal(t, 401, remoteError.StatusCode)
+	})
+	t.Run("register before onboard", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"register", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+	})
+	t.Run("not registered", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"resolve", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitRemote, commands.ExitCode(err))
+	})
+	t.Run("not onboarded", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"token", "--did", did}, flags...)...)
+		assert.Error(t, err)
+	})
+}
+
+func TestSetup(t *testing.T) {
+	server, flags := newMockServer(t)
+
+	t.Run("new did", func(t *testing.T) {
+		setup := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--token", "onboarding-token"}, flags...)...))
+		assert.Equal(t, []interface{}{"create", "onboard", "register", "resolve"}, setup["steps"])
+		assert.Equal(t, true, setup["verified"])
+		_, found := server.Document(setup["did"].(string))
+		assert.True(t, found)
+	})
+	t.Run("resume", func(t *testing.T) {
+		did := createDid(t, flags)
+		_, err := executeCommand(t, append([]string{"setup", "--non-interactive", "--token", "invalid-token"}, flags...)...)
+		assert.Equal(t, commands.ExitRemote, commands.ExitCode(err))
+
+		setup := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--token", "onboarding-token"}, flags...)...))
+		assert.Equal(t, did, setup["did"])
+		assert.Equal(t, []interface{}{"onboard", "register", "resolve"}, setup["steps"])
+		assert.Equal(t, "registered", setup["state"])
+	})
+	t.Run("registered did", func(t *testing.T) {
+		setup := map[string]interface{}{}
+		listed := []map[string]string{}
+		require.NoError(t, executeJson(t, &listed, append([]string{"list"}, flags...)...))
+		assert.NoError(t, executeJson(t, &setup, append([]string{"setup", "--non-interactive", "--did", listed[0]["did"]}, flags...)...))
+		assert.Equal(t, []interface{}{"resolve"}, setup["steps"])
+		assert.Equal(t, true, setup["verified"])
+	})
+}
+
+func TestDeactivate(t *testing.T) {
+	server, flags := newMockServer(t)
+	did := setupDid(t, flags)
+
+	t.Run("registered", func(t *testing.T) {
+		deactivated := map[string]interface{}{}
+		assert.NoError(t, executeJson(t, &deactivated, append([]string{"deactivate", "--did", did, "--yes"}, flags...)...))
+		assert.Equal(t, "deactivated", deactivated["state"])
+		assert.Equal(t, true, deactivated["onLedger"])
+		assert.NotEmpty(t, deactivated["transactionHash"])
+
+		metadata, found := server.Metadata(did)
+		require.True(t, found)
+		assert.Equal(t, true, metadata["deactivated"])
+	})
+	t.Run("no signing", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"token", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+		_, err = executeCommand(t, append([]string{"document", "add-key", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+	})
+	t.Run("already deactivated", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"deactivate", "--did", did, "--yes"}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+	})
+}
+
+func TestKeysExportLedger(t *testing.T) {
+	_, flags := newMockServer(t)
+	did := setupDid(t, flags)
+
+	exported := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &exported, append([]string{"keys", "export", "--did", did, "--format", "pem", "--include-private", "--yes"}, flags...)...))
+	assert.Len(t, exported["keys"], 5)
+	rest := []byte(exported["content"].(string))
+	for range exported["keys"].([]interface{}) {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		require.NotNil(t, block)
+		assert.Equal(t, "EC PRIVATE KEY", block.Type)
+	}
+}
+
+func TestDeactivateLedgerFailure(t *testing.T) {
+	fail, flags := newFailingMockServer(t)
+	did := setupDid(t, flags)
+	accessToken := storedAccessToken(t, flags, did)
+
+	fail.Store(true)
+	_, err := executeCommand(t, append([]string{"deactivate", "--did", did, "--yes"}, flags...)...)
+	assert.Equal(t, commands.ExitRemote, commands.ExitCode(err))
+	assert.NotEqual(t, accessToken, storedAccessToken(t, flags, did), "the refreshed access token is saved")
+
+	fail.Store(false)
+	deactivated := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &deactivated, append([]string{"deactivate", "--did", did, "--yes"}, flags...)...))
+	assert.Equal(t, "deactivated", deactivated["state"])
+}
+
+func TestVcVerifyLedger(t *testing.T) {
+	_, flags := newMockServer(t)
+	did := setupDid(t, flags)
+	credentialFile := filepath.Join(t.TempDir(), "vc.jwt")
+
+	_, err := executeCommand(t, append([]string{"vc", "issue", "--did", did, "--subject", did, "--type", "VerifiableAttestation", "--out", credentialFile}, flags...)...)
+	require.NoError(t, err)
+
+	verdict := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &verdict, append([]string{"vc", "verify", credentialFile}, flags...)...))
+	assert.Equal(t, true, verdict["valid"])
+	assert.Equal(t, did, verdict["issuer"])
+
+	t.Run("signed before a rotation", func(t *testing.T) {
+		rotatedFile := filepath.Join(t.TempDir(), "rotated.jwt")
+		_, err := executeCommand(t, append([]string{"vc", "issue", "--did", did, "--subject", did, "--out", rotatedFile}, flags...)...)
+		require.NoError(t, err)
+		rotated := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &rotated, append([]string{"document", "rotate-key", "--did", did, "--key", "issuance"}, flags...)...))
+
+		verdict := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &verdict, append([]string{"vc", "verify", rotatedFile}, flags...)...))
+		assert.Equal(t, true, verdict["valid"])
+		assert.Equal(t, rotated["retiredKeyId"], verdict["kid"])
+	})
+}
+
+func TestSignVerifyLedger(t *testing.T) {
+	_, flags := newMockServer(t)
+	did := setupDid(t, flags)
+	dir := t.TempDir()
+	payloadFile := filepath.Join(dir, "payload.json")
+	require.NoError(t, os.WriteFile(payloadFile, []byte(`{"nonce": "n-0S6_WzA2Mj"}`), 0600))
+
+	t.Run("signing key", func(t *testing.T) {
+		signedFile := filepath.Join(dir, "signing.jws")
+		signed := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &signed, append([]string{"sign", "--did", did, "--key", "signing", "--alg", "ES256K", "--in", payloadFile, "--out", signedFile}, flags...)...))
+		assert.Equal(t, "ES256K", signed["alg"])
+		verified := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &verified, append([]string{"verify", signedFile, "--did", did}, flags...)...))
+		assert.Equal(t, true, verified["valid"])
+	})
+	t.Run("issuance key", func(t *testing.T) {
+		signedFile := filepath.Join(dir, "issuance.jws")
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--in", payloadFile, "--out", signedFile}, flags...)...)
+		require.NoError(t, err)
+		verified := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &verified, append([]string{"verify", signedFile}, flags...)...))
+		assert.Equal(t, "did document", verified["source"])
+	})
+	t.Run("signed before a rotation", func(t *testing.T) {
+		jwtFile := filepath.Join(dir, "claims.json")
+		require.NoError(t, os.WriteFile(jwtFile, []byte(fmt.Sprintf(`{"iat": %d, "nonce": "n-0S6_WzA2Mj"}`, time.Now().Unix())), 0600))
+		signedFile, compactFile := filepath.Join(dir, "retired.jws"), filepath.Join(dir, "no-iat.jws")
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--in", jwtFile, "--out", signedFile}, flags...)...)
+		require.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"sign", "--did", did, "--in", payloadFile, "--out", compactFile}, flags...)...)
+		require.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"document", "rotate-key", "--did", did, "--key", "issuance"}, flags...)...)
+		require.NoError(t, err)
+
+		verified := map[string]interface{}{}
+		require.NoError(t, executeJson(t, &verified, append([]string{"verify", signedFile}, flags...)...))
+		assert.Equal(t, true, verified["valid"])
+		assert.Equal(t, "key history", verified["source"])
+		_, err = executeCommand(t, append([]string{"verify", compactFile}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidCredential, commands.ExitCode(err))
+	})
+}
+
+func TestVpLedger(t *testing.T) {
+	_, flags := newMockServer(t)
+	did := setupDid(t, flags)
+	dir := t.TempDir()
+	credentialFile := filepath.Join(dir, "vc.jwt")
+	presentationFile := filepath.Join(dir, "vp.jwt")
+
+	_, err := executeCommand(t, append([]string{"vc", "issue", "--did", did, "--subject", did, "--out", credentialFile}, flags...)...)
+	require.NoError(t, err)
+	_, err = executeCommand(t, append([]string{"vp", "create", "--did", did, "--vc", credentialFile, "--audience", "https://verifier.example.org", "--nonce", "n-0S6_WzA2Mj", "--out", presentationFile}, flags...)...)
+	require.NoError(t, err)
+
+	verdict := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &verdict, append([]string{"vp", "verify", presentationFile, "--audience", "https://verifier.example.org", "--nonce", "n-0S6_WzA2Mj"}, flags...)...))
+	assert.Equal(t, true, verdict["valid"])
+	assert.Equal(t, did, verdict["holder"])
+}
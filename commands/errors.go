@@ -0,0 +1,167 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/ebsiapi"
+	"github.com/gossif/admin/wallet"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Exit codes of the cli, every kind of failure has its own exit code
+const (
+	ExitOK                = 0
+	ExitFailure           = 1
+	ExitUsage             = 2
+	ExitInvalidDid        = 3
+	ExitNotFound          = 4
+	ExitWalletLocked      = 5
+	ExitRemote            = 6
+	ExitConfig            = 7
+	ExitInvalidState      = 8
+	ExitInvalidCredential = 9
+)
+
+// errNaturalPerson is returned when a ledger operation is requested for the identifier of a natural person
+var errNaturalPerson = errors.New("the identifier of a natural person is not registered on the ledger")
+
+// errInvalidSignature is returned when the signature of a jws is not valid
+var errInvalidSignature = errors.New("invalid signature")
+
+// UsageError is returned on invalid flags or arguments
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidDidError is returned when the identifier is not valid
+type InvalidDidError struct {
+	Did string
+	Err error
+}
+
+func (e *InvalidDidError) Error() string {
+	return fmt.Sprintf("identifier %q is not valid: %s", e.Did, e.Err)
+}
+
+func (e *InvalidDidError) Unwrap() error {
+	return e.Err
+}
+
+// RemoteError is returned when the ebsi api responds with an error
+type RemoteError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *RemoteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RemoteError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned when the configuration can't be loaded
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return "failed to load the configuration: " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the error
+func ExitCode(err error) int {
+	var (
+		usageError      *UsageError
+		invalidDidError *InvalidDidError
+		remoteError     *RemoteError
+		apiError        *ebsiapi.APIError
+		configError     *ConfigError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageError):
+		return ExitUsage
+	case errors.As(err, &invalidDidError):
+		return ExitInvalidDid
+	case errors.Is(err, wallet.ErrWalletLocked), errors.Is(err, wallet.ErrInvalidPassphrase):
+		return ExitWalletLocked
+	case errors.Is(err, wallet.ErrNotFound):
+		return ExitNotFound
+	case errors.As(err, &remoteError), errors.As(err, &apiError):
+		return ExitRemote
+	case errors.As(err, &configError):
+		return ExitConfig
+	case errors.Is(err, wallet.ErrInvalidTransition), errors.Is(err, wallet.ErrDidDeactivated):
+		return ExitInvalidState
+	case errors.Is(err, credential.ErrInvalidCredential), errors.Is(err, credential.ErrInvalidPresentation), errors.Is(err, errInvalidSignature):
+		return ExitInvalidCredential
+	default:
+		return ExitFailure
+	}
+}
+
+// remoteError wraps the error of a request to the ebsi apis. The ebsi package formats the http status
+// as "request_failed: <status in binary> <status text>".
+func remoteError(err error) error {
+	var (
+		urlError     *url.Error
+		httpError    *jsonrpc.HTTPError
+		rpcError     *jsonrpc.RPCError
+		apiError     *ebsiapi.APIError
+		registryErr  *ebsiapi.RPCError
+		remoteFailed bool
+		statusCode   int
+	)
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.As(err, &apiError):
+		return &RemoteError{StatusCode: apiError.StatusCode, Err: err}
+	case errors.As(err, &registryErr):
+		return &RemoteError{Err: err}
+	}
+	if strings.HasPrefix(err.Error(), "request_failed: ") {
+		remoteFailed = true
+		if fields := strings.Fields(strings.TrimPrefix(err.Error(), "request_failed: ")); len(fields) > 0 {
+			if code, parseErr := strconv.ParseInt(fields[0], 2, 32); parseErr == nil {
+				statusCode = int(code)
+				err = fmt.Errorf("request_failed: %d %s", statusCode, http.StatusText(statusCode))
+			}
+		}
+	}
+	switch {
+	case errors.As(err, &httpError):
+		remoteFailed, statusCode = true, httpError.Code
+	case errors.As(err, &rpcError), errors.As(err, &urlError):
+		remoteFailed = true
+	}
+	if !remoteFailed {
+		return err
+	}
+	return &RemoteError{StatusCode: statusCode, Err: err}
+}
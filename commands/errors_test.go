@@ -0,0 +1,44 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/ebsiapi"
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "no error", err: nil, want: commands.ExitOK},
+		{name: "unknown error", err: errors.New("failed"), want: commands.ExitFailure},
+		{name: "usage", err: &commands.UsageError{Err: errors.New("unknown flag: --foo")}, want: commands.ExitUsage},
+		{name: "invalid did", err: &commands.InvalidDidError{Did: "foo", Err: errors.New("invalid_schema")}, want: commands.ExitInvalidDid},
+		{name: "bucket not found", err: fmt.Errorf("failed to load the did bucket: %w", wallet.ErrNotFound), want: commands.ExitNotFound},
+		{name: "wallet locked", err: fmt.Errorf("failed to unlock the wallet: %w", wallet.ErrWalletLocked), want: commands.ExitWalletLocked},
+		{name: "invalid passphrase", err: fmt.Errorf("failed to unlock the wallet: %w", wallet.ErrInvalidPassphrase), want: commands.ExitWalletLocked},
+		{name: "remote", err: fmt.Errorf("failed to register: %w", &commands.RemoteError{StatusCode: 500, Err: errors.New("request_failed")}), want: commands.ExitRemote},
+		{name: "api", err: fmt.Errorf("failed to request the access token: %w", &ebsiapi.APIError{StatusCode: 401}), want: commands.ExitRemote},
+		{name: "config", err: &commands.ConfigError{Err: errors.New("unknown environment")}, want: commands.ExitConfig},
+		{name: "invalid state", err: wallet.CanChangeState(wallet.StateCreated, wallet.StateRegistered), want: commands.ExitInvalidState},
+		{name: "deactivated", err: fmt.Errorf("failed to sign: %w", wallet.ErrDidDeactivated), want: commands.ExitInvalidState},
+		{name: "invalid credential", err: credential.Verdict{Checks: []credential.Check{{Name: credential.CheckSignature}}}.Err(), want: commands.ExitInvalidCredential},
+		{name: "invalid presentation", err: credential.PresentationVerdict{Checks: []credential.Check{{Name: credential.CheckNonce}}}.Err(), want: commands.ExitInvalidCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, commands.ExitCode(tt.err))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the did with its keys, document, token and state to a passphrase encrypted file.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		outFile, _ := cmd.Flags().GetString("out")
+		if outFile == "" {
+			return &UsageError{Err: errors.New("the file of the export is missing, set it with --out")}
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		passphrase, err := exportPassphrase(true)
+		if err != nil {
+			return err
+		}
+		exported, err := wallet.ExportBucket(did, passphrase)
+		if errors.Is(err, wallet.ErrWeakPassphrase) {
+			return &UsageError{Err: err}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export the did %s: %w", did, err)
+		}
+		if err = os.WriteFile(outFile, exported, 0600); err != nil {
+			return fmt.Errorf("failed to write the export: %w", err)
+		}
+		return printResult(cmd, exportResult{Did: did, File: outFile})
+	},
+}
+
+var ImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import the did of a passphrase encrypted file into the wallet.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		exported, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read the export: %w", err)
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		passphrase, err := exportPassphrase(false)
+		if err != nil {
+			return err
+		}
+		didBucket, envelope, err := wallet.DecryptExport(exported, passphrase)
+		if errors.Is(err, wallet.ErrInvalidExportPassphrase) {
+			return &UsageError{Err: fmt.Errorf("failed to decrypt the export: %w", err)}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the export: %w", err)
+		}
+		overwritten, err := wallet.ImportBucket(didBucket, overwrite)
+		if errors.Is(err, wallet.ErrBucketExists) {
+			return &UsageError{Err: fmt.Errorf("failed to import the did %s: %w, replace it with --overwrite", didBucket.Did, err)}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import the did %s: %w", didBucket.Did, err)
+		}
+		return printResult(cmd, importResult{Did: didBucket.Did, State: didBucket.State, ExportedAt: envelope.ExportedAt, Overwritten: overwritten})
+	},
+}
+
+func init() {
+	ExportCmd.Flags().StringP("did", "d", "", "the did to export.")
+	ExportCmd.Flags().StringP("out", "o", "", "the file of the export.")
+	ImportCmd.Flags().Bool("overwrite", false, "replace the did in the wallet, the replaced did is moved to the trash.")
+}
+
+// exportPassphrase returns the passphrase of the export from ESSIF_EXPORT_PASSPHRASE or the prompt, a new
+// passphrase is repeated
+func exportPassphrase(repeat bool) (string, error) {
+	if passphrase, ok := os.LookupEnv("ESSIF_EXPORT_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+	if !isTerminal() {
+		return "", &UsageError{Err: errors.New("stdin is not a terminal, set the passphrase of the export with ESSIF_EXPORT_PASSPHRASE")}
+	}
+	passphrase := passwordPrompt("Please provide the passphrase of the export.")
+	if repeat && passwordPrompt("Please repeat the passphrase.") != passphrase {
+		return "", errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// exportResult is the result of the export command
+type exportResult struct {
+	Did  string `json:"did"`
+	File string `json:"file"`
+}
+
+func (r exportResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "The did %s is exported to %s\n", r.Did, r.File)
+}
+
+func (r exportResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "file"}, [][]string{{r.Did, r.File}}
+}
+
+// importResult is the result of the import command
+type importResult struct {
+	Did         string    `json:"did"`
+	State       string    `json:"state"`
+	ExportedAt  time.Time `json:"exportedAt"`
+	Overwritten bool      `json:"overwritten"`
+}
+
+func (r importResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "The did %s is imported\n", r.Did)
+}
+
+func (r importResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "state", "exported at"}, [][]string{{r.Did, r.State, r.ExportedAt.Format(time.RFC3339)}}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	exportFile := filepath.Join(t.TempDir(), "did.jwe")
+	sourceFlags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+	targetFlags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+
+	did := createDid(t, sourceFlags, "--method", "key")
+
+	t.Run("no passphrase", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"export", "--did", did, "--out", exportFile}, sourceFlags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("export", func(t *testing.T) {
+		t.Setenv("ESSIF_EXPORT_PASSPHRASE", "export passphrase")
+		_, err := executeCommand(t, append([]string{"export", "--did", did, "--out", exportFile}, sourceFlags...)...)
+		assert.NoError(t, err)
+	})
+	t.Run("invalid passphrase", func(t *testing.T) {
+		t.Setenv("ESSIF_EXPORT_PASSPHRASE", "another passphrase")
+		_, err := executeCommand(t, append([]string{"import", exportFile}, targetFlags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("import", func(t *testing.T) {
+		t.Setenv("ESSIF_EXPORT_PASSPHRASE", "export passphrase")
+		output, err := executeCommand(t, append([]string{"import", exportFile, "--overwrite"}, targetFlags...)...)
+		require.NoError(t, err)
+		imported := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &imported))
+		assert.Equal(t, did, imported["did"])
+		assert.Equal(t, "created", imported["state"])
+		assert.Equal(t, false, imported["overwritten"])
+
+		_, err = executeCommand(t, append([]string{"status", "--did", did}, targetFlags...)...)
+		assert.NoError(t, err)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		t.Setenv("ESSIF_EXPORT_PASSPHRASE", "export passphrase")
+		_, err := executeCommand(t, append([]string{"import", exportFile}, targetFlags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+		output, err := executeCommand(t, append([]string{"import", exportFile, "--overwrite"}, targetFlags...)...)
+		require.NoError(t, err)
+		imported := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &imported))
+		assert.Equal(t, true, imported["overwritten"])
+	})
+}
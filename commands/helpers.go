@@ -5,10 +5,18 @@ package commands
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
+
+	"github.com/gossif/admin/config"
+	"github.com/gossif/admin/wallet"
+	"github.com/gossif/ebsi"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // StringPrompt asks for a string value using the label
@@ -16,11 +24,12 @@ func stringPrompt(label string) string {
 	var s string
 	r := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Println(label)
-		fmt.Print(">")
+		fmt.Fprintln(os.Stderr, label)
+		fmt.Fprint(os.Stderr, ">")
 
-		s, _ = r.ReadString('\n')
-		if s != "" {
+		var err error
+		s, err = r.ReadString('\n')
+		if s != "" || err != nil {
 			break
 		}
 	}
@@ -33,3 +42,180 @@ func promptGetAccessToken() string {
 	accessToken := re.ReplaceAllString(promptToken, "")
 	return accessToken
 }
+
+// confirmPrompt asks for a confirmation using the label, the default answer is yes
+func confirmPrompt(label string) bool {
+	answer := strings.ToLower(stringPrompt(label + " [Y/n]"))
+	return answer == "" || strings.HasPrefix(answer, "y")
+}
+
+// confirmDestructivePrompt asks for a confirmation of a change that can't be undone, the default answer is no
+func confirmDestructivePrompt(label string) bool {
+	answer := strings.ToLower(stringPrompt(label + " [y/N]"))
+	return strings.HasPrefix(answer, "y")
+}
+
+// onboardToken returns the onboarding token of the --token flag, the file of the --token-file flag,
+// ESSIF_ONBOARD_TOKEN or stdin. The token is only prompted for when interactive and stdin is a terminal.
+func onboardToken(cmd *cobra.Command, interactive bool) (string, error) {
+	re := regexp.MustCompile(`\s+`)
+	token, _ := cmd.Flags().GetString("token")
+	if tokenFile, _ := cmd.Flags().GetString("token-file"); token == "" && tokenFile != "" {
+		tokenBytes, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		token = string(tokenBytes)
+	}
+	if token == "" {
+		token = os.Getenv("ESSIF_ONBOARD_TOKEN")
+	}
+	switch {
+	case token != "":
+	case !isTerminal():
+		tokenBytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		token = string(tokenBytes)
+	case interactive:
+		token = promptGetAccessToken()
+	}
+	token = re.ReplaceAllString(token, "")
+	if token == "" {
+		return "", errors.New("the onboarding token is missing, use --token, --token-file, ESSIF_ONBOARD_TOKEN or pipe it to stdin")
+	}
+	return token, nil
+}
+
+// readFileOrStdin returns the content of the file, or of stdin when the name is -
+func readFileOrStdin(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// isTerminal returns true when stdin is a terminal
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// passwordPrompt asks for a secret value using the label without echoing the input
+func passwordPrompt(label string) string {
+	fmt.Fprintln(os.Stderr, label)
+	fmt.Fprint(os.Stderr, ">")
+	secret, _ := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(string(secret))
+}
+
+// loadConfig returns the environment selected with the --env flag or ESSIF_ENV
+func loadConfig(cmd *cobra.Command) (config.Environment, error) {
+	env, err := config.Load(cmd.Flags())
+	if err != nil {
+		return config.Environment{}, &ConfigError{Err: err}
+	}
+	return env, nil
+}
+
+// ebsiDidFlag returns the ebsi identifier of the --did flag
+func ebsiDidFlag(cmd *cobra.Command) (string, error) {
+	didString, _ := cmd.Flags().GetString("did")
+	did := ebsi.NewDecentralizedIdentifier()
+	if err := did.ParseIdentifier(didString); err != nil {
+		return "", &InvalidDidError{Did: didString, Err: err}
+	}
+	return did.String(), nil
+}
+
+// didMethod returns the method of the decentralized identifier
+func didMethod(did string) string {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// didFlag returns the decentralized identifier of the --did flag, the method is not validated
+func didFlag(cmd *cobra.Command) (string, error) {
+	didString, _ := cmd.Flags().GetString("did")
+	if parts := strings.SplitN(didString, ":", 3); len(parts) < 3 || parts[0] != "did" || parts[1] == "" || parts[2] == "" {
+		return "", &InvalidDidError{Did: didString, Err: errors.New("invalid_schema")}
+	}
+	return didString, nil
+}
+
+// openWallet opens the wallet of the selected environment, defaults to the current named wallet
+func openWallet(cmd *cobra.Command) error {
+	backend, location, err := walletLocation(cmd)
+	if err != nil {
+		return err
+	}
+	if err = wallet.Open(backend, location); err != nil {
+		return fmt.Errorf("failed to open the wallet: %w", err)
+	}
+	return nil
+}
+
+// openExistingWallet opens the wallet like openWallet, but returns false instead of creating a wallet that doesn't exist
+func openExistingWallet(cmd *cobra.Command) (bool, error) {
+	backend, location, err := walletLocation(cmd)
+	if err != nil {
+		return false, err
+	}
+	if backend != wallet.BackendMemory {
+		if _, err = os.Stat(location); errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	if err = wallet.Open(backend, location); err != nil {
+		return false, fmt.Errorf("failed to open the wallet: %w", err)
+	}
+	return true, nil
+}
+
+// walletLocation returns the backend and the location of the wallet of the selected environment
+func walletLocation(cmd *cobra.Command) (string, string, error) {
+	env, err := loadConfig(cmd)
+	if err != nil {
+		return "", "", err
+	}
+	walletName := env.Wallet
+	if walletName == "" {
+		if walletName, err = wallet.CurrentName(); err != nil {
+			return "", "", err
+		}
+	}
+	location, err := wallet.Location(env.Backend, walletName)
+	if err != nil {
+		return "", "", err
+	}
+	return env.Backend, location, nil
+}
+
+// unlockWallet unlocks the wallet with the passphrase from ESSIF_PASSPHRASE or asks for it.
+// A new wallet asks twice for the passphrase to protect it with.
+func unlockWallet() error {
+	if passphrase, ok := os.LookupEnv("ESSIF_PASSPHRASE"); ok {
+		return wallet.Unlock(passphrase)
+	}
+	if !isTerminal() {
+		return fmt.Errorf("%w: stdin is not a terminal, set the passphrase with ESSIF_PASSPHRASE", wallet.ErrWalletLocked)
+	}
+	initialized, err := wallet.IsInitialized()
+	if err != nil {
+		return err
+	}
+	if initialized {
+		return wallet.Unlock(passwordPrompt("Please provide the passphrase of the wallet."))
+	}
+	passphrase := passwordPrompt("Please choose a passphrase to protect the wallet.")
+	if passwordPrompt("Please repeat the passphrase.") != passphrase {
+		return errors.New("the passphrases do not match")
+	}
+	return wallet.Unlock(passphrase)
+}
@@ -0,0 +1,309 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/cobra"
+)
+
+// Formats of the keys export command
+const (
+	KeyFormatJwks        string = "jwks"
+	KeyFormatPem         string = "pem"
+	KeyFormatMultibase   string = "multibase"
+	KeyFormatDidDocument string = "did-document"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// oidNamedCurves are the object identifiers of the curves, see RFC 5480 and SEC 2
+	oidNamedCurves = map[jwa.EllipticCurveAlgorithm]asn1.ObjectIdentifier{
+		jwa.P256: {1, 2, 840, 10045, 3, 1, 7},
+		jwa.EllipticCurveAlgorithm(did.KeyTypeSecp256k1): {1, 3, 132, 0, 10},
+	}
+)
+
+var KeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys of the dids in the wallet.",
+}
+
+var KeysExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the issuance, presentation and admin keys of the did as jwks, pem, multibase or did document.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		didSubject, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		format, _ := cmd.Flags().GetString("format")
+		includePrivate, _ := cmd.Flags().GetBool("include-private")
+		confirmed, _ := cmd.Flags().GetBool("yes")
+		outFile, _ := cmd.Flags().GetString("out")
+		switch format {
+		case KeyFormatJwks, KeyFormatPem:
+		case KeyFormatMultibase, KeyFormatDidDocument:
+			if includePrivate {
+				return &UsageError{Err: fmt.Errorf("the %s format has no private keys", format)}
+			}
+		default:
+			return &UsageError{Err: fmt.Errorf("unsupported format: %s", format)}
+		}
+		if includePrivate && !confirmed && !isTerminal() {
+			return &UsageError{Err: errors.New("confirm the export of the private keys with --yes")}
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(didSubject)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", didSubject, err)
+		}
+		if includePrivate && !confirmed {
+			if !confirmDestructivePrompt(fmt.Sprintf("Export the private keys of %s? Anyone with the keys can sign for the did.", didSubject)) {
+				return errors.New("the export is cancelled")
+			}
+		}
+		exported := keysExportResult{Did: didSubject, Format: format, File: outFile, Keys: exportKeys(didBucket)}
+		if exported.Content, err = formatKeys(didBucket, exported.Keys, format, includePrivate); err != nil {
+			return fmt.Errorf("failed to export the keys: %w", err)
+		}
+		if outFile != "" {
+			if err = os.WriteFile(outFile, []byte(exported.Content), 0600); err != nil {
+				return fmt.Errorf("failed to write the keys: %w", err)
+			}
+			// the keys are in the file only, not in the output
+			exported.Content = ""
+		}
+		return printResult(cmd, exported)
+	},
+}
+
+func init() {
+	KeysCmd.AddCommand(KeysExportCmd)
+	KeysExportCmd.Flags().StringP("did", "d", "", "the did of the keys.")
+	KeysExportCmd.Flags().StringP("format", "f", KeyFormatJwks, "the format of the keys (jwks, pem, multibase, did-document).")
+	KeysExportCmd.Flags().Bool("include-private", false, "export the private keys, for the jwks and pem format.")
+	KeysExportCmd.Flags().Bool("yes", false, "export the private keys without the confirmation prompt.")
+	KeysExportCmd.Flags().StringP("out", "o", "", "the file of the keys, defaults to stdout.")
+}
+
+// exportedKey is a key of the bucket with its role
+type exportedKey struct {
+	Id      string `json:"id"`
+	Role    string `json:"role"`
+	KeyType string `json:"keyType"`
+	key     jwk.Key
+}
+
+// keysExportResult is the result of the keys export command, the text output is the content. The content is
+// empty when the keys are written to a file.
+type keysExportResult struct {
+	Did     string        `json:"did"`
+	Format  string        `json:"format"`
+	File    string        `json:"file,omitempty"`
+	Keys    []exportedKey `json:"keys"`
+	Content string        `json:"content,omitempty"`
+}
+
+func (r keysExportResult) writeText(w io.Writer) {
+	if r.File != "" {
+		fmt.Fprintf(w, "Exported %d keys of %s to %s\n", len(r.Keys), r.Did, r.File)
+		return
+	}
+	fmt.Fprint(w, r.Content)
+}
+
+func (r keysExportResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, key := range r.Keys {
+		rows = append(rows, []string{key.Id, key.Role, key.KeyType})
+	}
+	return []string{"id", "role", "key type"}, rows
+}
+
+// exportKeys returns the issuance, presentation, admin and added keys of the bucket, a key used for both
+// issuance and presentation is exported once
+func exportKeys(didBucket wallet.DidBucket) []exportedKey {
+	keys := []exportedKey{}
+	for _, bucketKey := range []struct {
+		role string
+		key  jwk.Key
+	}{
+		{role: wallet.KeyRoleIssuance, key: didBucket.IssuanceKey},
+		{role: wallet.KeyRolePresentation, key: didBucket.PresentationKey},
+		{role: "adminSigning", key: didBucket.AdminSigningKey},
+		{role: "adminTransaction", key: didBucket.AdminTransactionKey},
+		{role: "adminEncryption", key: didBucket.AdminEncryptionKey},
+	} {
+		if bucketKey.key == nil {
+			continue
+		}
+		if n := len(keys); n > 0 && keys[n-1].Id == bucketKey.key.KeyID() {
+			continue
+		}
+		keys = append(keys, exportedKey{Id: bucketKey.key.KeyID(), Role: bucketKey.role, KeyType: keyCurve(bucketKey.key), key: bucketKey.key})
+	}
+	for _, verificationKey := range didBucket.VerificationKeys {
+		keys = append(keys, exportedKey{Id: verificationKey.Key.KeyID(), Role: "verification", KeyType: keyCurve(verificationKey.Key), key: verificationKey.Key})
+	}
+	return keys
+}
+
+// formatKeys returns the keys in the format, the private keys are included for the jwks and pem format only
+func formatKeys(didBucket wallet.DidBucket, keys []exportedKey, format string, includePrivate bool) (string, error) {
+	exportKey := func(key jwk.Key) (jwk.Key, error) {
+		if includePrivate {
+			return key, nil
+		}
+		return key.PublicKey()
+	}
+	switch format {
+	case KeyFormatJwks:
+		keySet := jwk.NewSet()
+		for _, key := range keys {
+			jwkKey, err := exportKey(key.key)
+			if err != nil {
+				return "", err
+			}
+			if err = keySet.AddKey(jwkKey); err != nil {
+				return "", err
+			}
+		}
+		return marshalIndent(keySet)
+	case KeyFormatPem:
+		var pemBytes bytes.Buffer
+		for _, key := range keys {
+			block, err := pemBlock(key.key, includePrivate)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", key.Id, err)
+			}
+			// the explanatory text before the block is ignored by the pem parsers, see RFC 7468
+			fmt.Fprintf(&pemBytes, "%s (%s)\n", key.Id, key.Role)
+			if err = pem.Encode(&pemBytes, block); err != nil {
+				return "", err
+			}
+		}
+		return pemBytes.String(), nil
+	case KeyFormatMultibase:
+		var lines bytes.Buffer
+		for _, key := range keys {
+			publicKey, err := key.key.PublicKey()
+			if err != nil {
+				return "", err
+			}
+			encoded, err := did.MultibasePublicKey(publicKey)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", key.Id, err)
+			}
+			fmt.Fprintf(&lines, "%s %s\n", key.Id, encoded)
+		}
+		return lines.String(), nil
+	default:
+		document := didBucket.Document
+		if document == nil {
+			publicKeys := []jwk.Key{}
+			for _, key := range keys {
+				if key.Role == wallet.KeyRoleIssuance || key.Role == wallet.KeyRolePresentation {
+					publicKey, err := key.key.PublicKey()
+					if err != nil {
+						return "", err
+					}
+					publicKeys = append(publicKeys, publicKey)
+				}
+			}
+			document = did.NewDocument(didBucket.Did, publicKeys...)
+		}
+		return marshalIndent(document)
+	}
+}
+
+// marshalIndent returns the indented json with a trailing new line
+func marshalIndent(v interface{}) (string, error) {
+	jsonBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes) + "\n", nil
+}
+
+// pemBlock returns the public key as SubjectPublicKeyInfo (RFC 5480) or the private key as SEC 1 EC private key
+// (RFC 5915). The keys are encoded without crypto/x509, it doesn't support the secp256k1 curve.
+func pemBlock(key jwk.Key, includePrivate bool) (*pem.Block, error) {
+	crv, _ := key.Get(jwk.ECDSACrvKey)
+	curveAlgorithm, _ := crv.(jwa.EllipticCurveAlgorithm)
+	oidCurve, ok := oidNamedCurves[curveAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported curve: %v", crv)
+	}
+	publicKey := ecdsa.PublicKey{}
+	if err := key.Raw(&publicKey); err != nil {
+		privateKey := ecdsa.PrivateKey{}
+		if err = key.Raw(&privateKey); err != nil {
+			return nil, err
+		}
+		publicKey = privateKey.PublicKey
+	}
+	byteLen := (publicKey.Curve.Params().BitSize + 7) / 8
+	point := make([]byte, 1+2*byteLen)
+	point[0] = 4
+	publicKey.X.FillBytes(point[1 : 1+byteLen])
+	publicKey.Y.FillBytes(point[1+byteLen:])
+	if includePrivate {
+		privateKey := ecdsa.PrivateKey{}
+		if err := key.Raw(&privateKey); err != nil {
+			return nil, err
+		}
+		der, err := asn1.Marshal(struct {
+			Version       int
+			PrivateKey    []byte
+			NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+			PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+		}{
+			Version:       1,
+			PrivateKey:    privateKey.D.FillBytes(make([]byte, byteLen)),
+			NamedCurveOID: oidCurve,
+			PublicKey:     asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+	}
+	curveParameters, err := asn1.Marshal(oidCurve)
+	if err != nil {
+		return nil, err
+	}
+	der, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curveParameters}},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "PUBLIC KEY", Bytes: der}, nil
+}
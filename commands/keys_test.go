@@ -0,0 +1,88 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysExport(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir()}
+
+	did := createDid(t, flags, "--method", "key")
+
+	t.Run("jwks", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did}, flags...)...)
+		require.NoError(t, err)
+		keySet := struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &keySet))
+		require.Len(t, keySet.Keys, 1)
+		assert.Equal(t, "P-256", keySet.Keys[0]["crv"])
+		assert.NotContains(t, keySet.Keys[0], "d")
+	})
+	t.Run("pem", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--format", "pem"}, flags...)...)
+		require.NoError(t, err)
+		block, _ := pem.Decode([]byte(output))
+		require.NotNil(t, block)
+		assert.Equal(t, "PUBLIC KEY", block.Type)
+		_, err = x509.ParsePKIXPublicKey(block.Bytes)
+		assert.NoError(t, err)
+	})
+	t.Run("multibase", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--format", "multibase"}, flags...)...)
+		require.NoError(t, err)
+		fields := strings.Fields(output)
+		require.Len(t, fields, 2)
+		assert.Equal(t, "did:key:"+fields[1], did)
+	})
+	t.Run("did-document", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--format", "did-document"}, flags...)...)
+		require.NoError(t, err)
+		document := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &document))
+		assert.Equal(t, did, document["id"])
+	})
+	t.Run("private", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--include-private"}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--format", "pem", "--include-private", "--yes"}, flags...)...)
+		require.NoError(t, err)
+		block, _ := pem.Decode([]byte(output))
+		require.NotNil(t, block)
+		assert.Equal(t, "EC PRIVATE KEY", block.Type)
+		_, err = x509.ParseECPrivateKey(block.Bytes)
+		assert.NoError(t, err)
+	})
+	t.Run("private to file", func(t *testing.T) {
+		keysFile := filepath.Join(t.TempDir(), "keys.jwks")
+		output, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--include-private", "--yes", "--out", keysFile, "--output", "json"}, flags...)...)
+		require.NoError(t, err)
+		exported := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &exported))
+		assert.NotContains(t, exported, "content")
+		assert.NotContains(t, output, `"d"`)
+
+		keysBytes, err := os.ReadFile(keysFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(keysBytes), `"d"`)
+	})
+	t.Run("private multibase", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"keys", "export", "--did", did, "--format", "multibase", "--include-private", "--yes"}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+}
@@ -5,7 +5,8 @@ package commands
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"text/tabwriter"
 
 	"github.com/gossif/admin/wallet"
 	"github.com/spf13/cobra"
@@ -15,14 +16,52 @@ var ListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List the decentralized identifiers stored in the wallet).",
 	Args:  cobra.ExactArgs(0),
-	Run: func(_ *cobra.Command, _ []string) {
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
 		identifiers, err := wallet.GetAllKeys()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error while reading the wallet\n'%s'\n", err)
-			return
+			return fmt.Errorf("failed to read the wallet: %w", err)
 		}
+		listed := listResult{}
 		for _, did := range identifiers {
-			fmt.Println(did)
+			didBucket, err := wallet.GetBucketByDid(did)
+			if err != nil {
+				return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+			}
+			listed = append(listed, didSummary{Did: did, Method: didMethod(did), State: didBucket.State})
 		}
+		return printResult(cmd, listed)
 	},
 }
+
+// didSummary describes a did in the wallet
+type didSummary struct {
+	Did    string `json:"did"`
+	Method string `json:"method"`
+	State  string `json:"state"`
+}
+
+// listResult is the result of the list command
+type listResult []didSummary
+
+func (r listResult) writeText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, summary := range r {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", summary.Did, summary.Method, summary.State)
+	}
+	tw.Flush()
+}
+
+func (r listResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, summary := range r {
+		rows = append(rows, []string{summary.Did, summary.Method, summary.State})
+	}
+	return []string{"did", "method", "state"}, rows
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gossif/admin/mock"
+	"github.com/spf13/cobra"
+)
+
+var MockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a local mock of the ebsi apis for testing, use it with --env local.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		onboardingToken, _ := cmd.Flags().GetString("onboarding-token")
+		server, err := mock.NewServer(mock.WithOnboardingToken(onboardingToken))
+		if err != nil {
+			return fmt.Errorf("failed to create the mock server: %w", err)
+		}
+		fmt.Printf("The mock of the ebsi apis is listening on http://%s\n", addr)
+		return http.ListenAndServe(addr, server)
+	},
+}
+
+func init() {
+	MockServerCmd.Flags().String("addr", "localhost:8080", "the address the mock server listens on.")
+	MockServerCmd.Flags().String("onboarding-token", "", "the only onboarding token accepted, defaults to any token.")
+}
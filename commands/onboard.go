@@ -9,6 +9,8 @@ import (
 
 	secp256k1v4 "github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/google/uuid"
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
 	"github.com/gossif/admin/wallet"
 	"github.com/gossif/ebsi"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -19,46 +21,83 @@ var OnboardCmd = &cobra.Command{
 	Use:   "onboard",
 	Short: "Step 2: Onboard the controller of the did.",
 	Args:  cobra.ExactArgs(0),
-	Run: func(cmd *cobra.Command, _ []string) {
-		didString, _ := cmd.Flags().GetString("did")
-		did := ebsi.NewDecentralizedIdentifier()
-		if err := did.ParseIdentifier(didString); err != nil {
-			fmt.Printf("Identifier is not valid\n'%s'\n", err)
-			return
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := ebsiDidFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if didkit.IsEbsiNaturalPerson(did) {
+			return &InvalidDidError{Did: did, Err: errNaturalPerson}
 		}
-		accessToken := promptGetAccessToken()
-		didBucket, err := wallet.GetBucketByDid(did.String())
+		env, err := loadConfig(cmd)
 		if err != nil {
-			fmt.Printf("Failed to load the did bucket\n'%s'\n", err)
-			return
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
 		}
-		didBucket.AdminSigningKey, _ = generateSecp256k1AsJwk(didBucket.Did)
-		ebsiTrustList := ebsi.NewEBSITrustList(
-			ebsi.WithBaseUrl("https://api-pilot.ebsi.eu"),
-			ebsi.WithVerbose(true),
-			ebsi.WithAuthToken(accessToken),
-		)
-		// token is a capthca token or a vc jwt
-		token, err := ebsiTrustList.Onboard(did.String(), didBucket.AdminSigningKey)
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
 		if err != nil {
-			fmt.Printf("failed to onboard the user\n'%s'\n", err)
-			return
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
 		}
-		switch token := token.(type) {
-		case string:
-			didBucket.Token = token
-			if err = wallet.StoreBucket(didBucket); err != nil {
-				fmt.Printf("failed to save the results\n'%s'\n", err)
-				return
-			}
-		default:
-			fmt.Printf("invalid response type\n'%s'\n", err)
+		if err := wallet.CanChangeState(didBucket.State, wallet.StateOnboarded); err != nil {
+			return err
 		}
-
-		fmt.Printf("Onboarding of %s succeeded\n", didString)
+		accessToken, err := onboardToken(cmd, true)
+		if err != nil {
+			return &UsageError{Err: fmt.Errorf("failed to read the onboarding token: %w", err)}
+		}
+		if err = onboardBucket(env, &didBucket, accessToken); err != nil {
+			return err
+		}
+		if err = wallet.StoreBucket(didBucket); err != nil {
+			return fmt.Errorf("failed to save the results: %w", err)
+		}
+		return printResult(cmd, stateResult{
+			Did:     did,
+			State:   didBucket.State,
+			message: fmt.Sprintf("Onboarding of %s succeeded", did),
+		})
 	},
 }
 
+func init() {
+	OnboardCmd.Flags().StringP("did", "d", "", "the did to be onboarded.")
+	OnboardCmd.Flags().StringP("token", "t", "", "the onboarding token, instead of the prompt.")
+	OnboardCmd.Flags().String("token-file", "", "the file with the onboarding token, instead of the prompt.")
+}
+
+// onboardBucket onboards the controller of the did with the onboarding token of the eu login or captcha,
+// the verifiable authorisation is stored as token in the bucket
+func onboardBucket(env config.Environment, didBucket *wallet.DidBucket, onboardingToken string) error {
+	if err := wallet.CanChangeState(didBucket.State, wallet.StateOnboarded); err != nil {
+		return err
+	}
+	didBucket.AdminSigningKey, _ = generateSecp256k1AsJwk(didBucket.Did)
+	ebsiTrustList := ebsi.NewEBSITrustList(
+		ebsi.WithBaseUrl(env.BaseUrl),
+		// the http client dumps the requests to stderr, the ebsi package dumps them to stdout
+		ebsi.WithVerbose(false),
+		ebsi.WithHttpClient(env.HttpClient()),
+		ebsi.WithAuthToken(onboardingToken),
+	)
+	// token is a capthca token or a vc jwt
+	token, err := ebsiTrustList.Onboard(didBucket.Did, didBucket.AdminSigningKey)
+	if err != nil {
+		return fmt.Errorf("failed to onboard the user: %w", remoteError(err))
+	}
+	verifiableAuthorisation, ok := token.(string)
+	if !ok {
+		return &RemoteError{Err: fmt.Errorf("invalid response type %T", token)}
+	}
+	didBucket.Token = verifiableAuthorisation
+	return didBucket.ChangeState(wallet.StateChange{State: wallet.StateOnboarded})
+}
+
 // generateSecp256k1AsJwk generates secp256k1 key pair and returns private key as json web key
 func generateSecp256k1AsJwk(didController string) (jwk.Key, error) {
 	rawKey, err := secp256k1v4.GeneratePrivateKey()
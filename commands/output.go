@@ -0,0 +1,125 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the --output flag
+const (
+	OutputText  = "text"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+	OutputTable = "table"
+)
+
+// result is the structured result of a command, the json tags are used for the json and yaml output
+type result interface {
+	// writeText writes the human readable result
+	writeText(w io.Writer)
+	// tableRows returns the header and the rows of the table output
+	tableRows() ([]string, [][]string)
+}
+
+// outputFormat returns the format of the --output flag
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	switch format {
+	case "", OutputText:
+		return OutputText, nil
+	case OutputJson, OutputYaml, OutputTable:
+		return format, nil
+	default:
+		return "", &UsageError{Err: fmt.Errorf("unsupported output format: %s", format)}
+	}
+}
+
+// ValidateOutput fails on an unsupported format of the --output flag, before the command has side effects
+func ValidateOutput(cmd *cobra.Command, _ []string) error {
+	_, err := outputFormat(cmd)
+	return err
+}
+
+// printResult writes the result to stdout in the format of the --output flag
+func printResult(cmd *cobra.Command, res result) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return writeResult(cmd.OutOrStdout(), format, res)
+}
+
+// writeResult writes the result in the format
+func writeResult(w io.Writer, format string, res result) error {
+	switch format {
+	case OutputJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(res)
+	case OutputYaml:
+		return writeYaml(w, res)
+	case OutputTable:
+		header, rows := res.tableRows()
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		res.writeText(w)
+		return nil
+	}
+}
+
+// writeYaml writes the result as yaml. The result is converted from json, so the json tags and
+// json marshalers (f.e. of the keys) are used and the order of the fields is preserved.
+func writeYaml(w io.Writer, res result) error {
+	jsonBytes, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(jsonBytes, &node); err != nil {
+		return err
+	}
+	resetYamlStyle(&node)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// resetYamlStyle resets the json flow style of the nodes to the yaml block style
+func resetYamlStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYamlStyle(child)
+	}
+}
+
+// stateResult is the result of a command that changes the state of a did
+type stateResult struct {
+	Did     string `json:"did"`
+	State   string `json:"state"`
+	message string
+}
+
+func (r stateResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.message)
+}
+
+func (r stateResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "state"}, [][]string{{r.Did, r.State}}
+}
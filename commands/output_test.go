@@ -0,0 +1,82 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+// newTestWallet returns the location of a directory wallet with the buckets
+func newTestWallet(t *testing.T, buckets ...wallet.DidBucket) string {
+	t.Helper()
+	location := t.TempDir()
+	assert.NoError(t, wallet.Open(wallet.BackendDirectory, location))
+	defer wallet.Close()
+	assert.NoError(t, wallet.Unlock(testPassphrase))
+	for _, bucket := range buckets {
+		assert.NoError(t, wallet.StoreBucket(bucket))
+	}
+	return location
+}
+
+func TestOutput(t *testing.T) {
+	location := newTestWallet(t,
+		wallet.DidBucket{Did: "did:ebsi:zfEmvX5twhXjQJiCWsukvQA", Token: "eyJ0eXAiOiJKV1QifQ"},
+		wallet.DidBucket{Did: "did:web:example.org"},
+	)
+	t.Run("text", func(t *testing.T) {
+		output, err := executeCommand(t, "list", "--backend", "dir", "--wallet", location)
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 2)
+		assert.ElementsMatch(t, [][]string{
+			{"did:ebsi:zfEmvX5twhXjQJiCWsukvQA", "ebsi", "onboarded"},
+			{"did:web:example.org", "web", "created"},
+		}, [][]string{strings.Fields(lines[0]), strings.Fields(lines[1])})
+	})
+	t.Run("json", func(t *testing.T) {
+		output, err := executeCommand(t, "list", "--backend", "dir", "--wallet", location, "--output", "json")
+		assert.NoError(t, err)
+		listed := []map[string]string{}
+		assert.NoError(t, json.Unmarshal([]byte(output), &listed))
+		assert.ElementsMatch(t, []map[string]string{
+			{"did": "did:ebsi:zfEmvX5twhXjQJiCWsukvQA", "method": "ebsi", "state": "onboarded"},
+			{"did": "did:web:example.org", "method": "web", "state": "created"},
+		}, listed)
+	})
+	t.Run("yaml", func(t *testing.T) {
+		output, err := executeCommand(t, "list", "--backend", "dir", "--wallet", location, "--output", "yaml")
+		assert.NoError(t, err)
+		listed := []map[string]string{}
+		assert.NoError(t, yaml.Unmarshal([]byte(output), &listed))
+		assert.Len(t, listed, 2)
+		assert.NotContains(t, output, "{")
+	})
+	t.Run("table", func(t *testing.T) {
+		output, err := executeCommand(t, "list", "--backend", "dir", "--wallet", location, "--output", "table")
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		assert.Len(t, lines, 3)
+		assert.Equal(t, []string{"DID", "METHOD", "STATE"}, strings.Fields(lines[0]))
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := executeCommand(t, "list", "--backend", "dir", "--wallet", location, "--output", "xml")
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("wallet list", func(t *testing.T) {
+		output, err := executeCommand(t, "wallet", "list", "--output", "json")
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[]`, output)
+	})
+}
@@ -6,7 +6,8 @@ package commands
 import (
 	"fmt"
 
-	"github.com/gossif/ebsi"
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
 	"github.com/gossif/admin/wallet"
 	"github.com/spf13/cobra"
 )
@@ -15,40 +16,59 @@ var RegisterCmd = &cobra.Command{
 	Use:   "register",
 	Short: "Step 3: Register the did document (ebsi only).",
 	Args:  cobra.ExactArgs(0),
-	Run: func(cmd *cobra.Command, _ []string) {
-		didString, _ := cmd.Flags().GetString("did")
-		did := ebsi.NewDecentralizedIdentifier()
-		if err := did.ParseIdentifier(didString); err != nil {
-			fmt.Printf("Identifier is not valid\n'%s'\n", err)
-			return
-		}
-		didBucket, err := wallet.GetBucketByDid(did.String())
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := ebsiDidFlag(cmd)
 		if err != nil {
-			fmt.Printf("Failed to load the did bucket\n'%s'\n", err)
-			return
-		}
-		didBucket.AdminEncryptionKey, _ = generateSecp256k1AsJwk(didBucket.Did)
-		didBucket.AdminTransactionKey, _ = generateSecp256k1AsJwk(didBucket.Did)
-		ebsiTrustList := ebsi.NewEBSITrustList(
-			ebsi.WithBaseUrl("https://api-pilot.ebsi.eu"),
-			ebsi.WithVerbose(true),
-		)
-		if _, err := ebsiTrustList.RegisterDid(
-			ebsi.WithController(didBucket.Did),
-			ebsi.WithDocument(didBucket.Document),
-			ebsi.WithDocumentMetadata(map[string]interface{}{"deactivated": false}),
-			ebsi.WithToken(didBucket.Token),
-			ebsi.WithEncryptionKey(didBucket.AdminEncryptionKey),
-			ebsi.WithSigningKey(didBucket.AdminSigningKey),
-			ebsi.WithTransactionKey(didBucket.AdminTransactionKey),
-		); err != nil {
-			fmt.Printf("Failed to register the did document\n'%s'\n", err)
-			return
+			return err
+		}
+		if didkit.IsEbsiNaturalPerson(did) {
+			return &InvalidDidError{Did: did, Err: errNaturalPerson}
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if err = registerBucket(env, &didBucket); err != nil {
+			return err
 		}
 		if err = wallet.StoreBucket(didBucket); err != nil {
-			fmt.Printf("Failed to save the results\n'%s'\n", err)
-			return
+			return fmt.Errorf("failed to save the results: %w", err)
 		}
-		fmt.Printf("Registering of the did document for %s succeeded", didString)
+		return printResult(cmd, stateResult{
+			Did:     did,
+			State:   didBucket.State,
+			message: fmt.Sprintf("Registering of the did document for %s succeeded", did),
+		})
 	},
 }
+
+func init() {
+	RegisterCmd.Flags().StringP("did", "d", "", "the did to be registered.")
+}
+
+// registerBucket registers the did document of the bucket in the did registry with a new admin transaction key
+func registerBucket(env config.Environment, didBucket *wallet.DidBucket) error {
+	if err := wallet.CanChangeState(didBucket.State, wallet.StateRegistered); err != nil {
+		return err
+	}
+	if err := ledgerAccessToken(env, didBucket); err != nil {
+		return err
+	}
+	didBucket.AdminTransactionKey, _ = generateSecp256k1AsJwk(didBucket.Did)
+	transactionHash, err := newEbsiClient(env).InsertDidDocument(didBucket.AccessToken, didBucket.Did, didBucket.Document, map[string]interface{}{"deactivated": false}, didBucket.AdminTransactionKey)
+	if err != nil {
+		return fmt.Errorf("failed to register the did document: %w", remoteError(err))
+	}
+	return didBucket.ChangeState(wallet.StateChange{State: wallet.StateRegistered, TransactionHash: transactionHash})
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var errRemovalCancelled = errors.New("the removal is cancelled")
+
+var RemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove the did from the wallet, the did is kept in the trash to be restored.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		permanent, _ := cmd.Flags().GetBool("permanent")
+		confirmed, _ := cmd.Flags().GetBool("yes")
+		if !confirmed && !isTerminal() {
+			return &UsageError{Err: errors.New("confirm the removal with --yes")}
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if isActiveOnLedger(didBucket) && !force {
+			return fmt.Errorf("%w: the did is %s in the did registry, deactivate it first or remove it with --force", wallet.ErrInvalidTransition, didBucket.State)
+		}
+		if !confirmed {
+			label := fmt.Sprintf("Remove the did %s and its keys from the wallet?", did)
+			if permanent {
+				label = fmt.Sprintf("Remove the did %s and its keys permanently? The removal can't be undone.", did)
+			}
+			if !confirmDestructivePrompt(label) {
+				return errRemovalCancelled
+			}
+		}
+		if err = wallet.RemoveBucket(did, permanent); err != nil {
+			return fmt.Errorf("failed to remove the did %s: %w", did, err)
+		}
+		if _, err = wallet.PurgeTrash(true); err != nil {
+			return fmt.Errorf("failed to purge the trash: %w", err)
+		}
+		removed := removeResult{Did: did, State: didBucket.State, Permanent: permanent}
+		if !permanent {
+			removed.RestorableUntil = time.Now().UTC().Add(wallet.TrashGracePeriod)
+		}
+		return printResult(cmd, removed)
+	},
+}
+
+var TrashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage the removed dids, the dids are purged after the grace period.",
+}
+
+var TrashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the removed dids that can be restored.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		if _, err := wallet.PurgeTrash(true); err != nil {
+			return fmt.Errorf("failed to purge the trash: %w", err)
+		}
+		trash, err := wallet.TrashedBuckets()
+		if err != nil {
+			return fmt.Errorf("failed to read the trash: %w", err)
+		}
+		listed := trashListResult{}
+		for _, trashed := range trash {
+			listed = append(listed, trashSummary{Did: trashed.Did, State: trashed.State, RemovedAt: trashed.RemovedAt, ExpiresAt: trashed.ExpiresAt()})
+		}
+		return printResult(cmd, listed)
+	},
+}
+
+var TrashRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the removed did to the wallet.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.RestoreBucket(did)
+		if errors.Is(err, wallet.ErrBucketExists) {
+			return &UsageError{Err: fmt.Errorf("failed to restore the did %s: %w", did, err)}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore the did %s: %w", did, err)
+		}
+		return printResult(cmd, restoreResult{Did: did, State: didBucket.State})
+	},
+}
+
+var TrashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Delete the removed dids and their keys permanently.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		confirmed, _ := cmd.Flags().GetBool("yes")
+		if !confirmed && !isTerminal() {
+			return &UsageError{Err: errors.New("confirm emptying the trash with --yes")}
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		if !confirmed && !confirmDestructivePrompt("Delete the removed dids and their keys permanently? The deletion can't be undone.") {
+			return errRemovalCancelled
+		}
+		purged, err := wallet.PurgeTrash(false)
+		if err != nil {
+			return fmt.Errorf("failed to empty the trash: %w", err)
+		}
+		return printResult(cmd, emptyTrashResult{Purged: purged})
+	},
+}
+
+func init() {
+	TrashCmd.AddCommand(TrashListCmd)
+	TrashCmd.AddCommand(TrashRestoreCmd)
+	TrashCmd.AddCommand(TrashEmptyCmd)
+	RemoveCmd.Flags().StringP("did", "d", "", "the did to remove.")
+	RemoveCmd.Flags().Bool("force", false, "remove a did that is registered and not deactivated.")
+	RemoveCmd.Flags().Bool("permanent", false, "delete the did and its copy in the trash.")
+	RemoveCmd.Flags().Bool("yes", false, "remove without the confirmation prompt.")
+	TrashRestoreCmd.Flags().StringP("did", "d", "", "the did to restore.")
+	TrashEmptyCmd.Flags().Bool("yes", false, "empty the trash without the confirmation prompt.")
+}
+
+// isActiveOnLedger returns true when the did is in the did registry and not deactivated
+func isActiveOnLedger(didBucket wallet.DidBucket) bool {
+	return didkit.IsEbsi(didBucket.Did) && (didBucket.State == wallet.StateRegistered || didBucket.State == wallet.StateUpdated)
+}
+
+// removeResult is the result of the remove command
+type removeResult struct {
+	Did             string    `json:"did"`
+	State           string    `json:"state"`
+	Permanent       bool      `json:"permanent"`
+	RestorableUntil time.Time `json:"restorableUntil,omitempty"`
+}
+
+func (r removeResult) writeText(w io.Writer) {
+	if r.Permanent {
+		fmt.Fprintf(w, "The did %s is removed permanently\n", r.Did)
+		return
+	}
+	fmt.Fprintf(w, "The did %s is moved to the trash, it can be restored until %s\n", r.Did, r.RestorableUntil.Format(time.RFC3339))
+}
+
+func (r removeResult) tableRows() ([]string, [][]string) {
+	restorableUntil := ""
+	if !r.Permanent {
+		restorableUntil = r.RestorableUntil.Format(time.RFC3339)
+	}
+	return []string{"did", "state", "permanent", "restorable until"},
+		[][]string{{r.Did, r.State, fmt.Sprint(r.Permanent), restorableUntil}}
+}
+
+// trashSummary describes a removed did in the trash
+type trashSummary struct {
+	Did       string    `json:"did"`
+	State     string    `json:"state"`
+	RemovedAt time.Time `json:"removedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// trashListResult is the result of the trash list command
+type trashListResult []trashSummary
+
+func (r trashListResult) writeText(w io.Writer) {
+	for _, summary := range r {
+		fmt.Fprintf(w, "%s (removed at %s)\n", summary.Did, summary.RemovedAt.Format(time.RFC3339))
+	}
+}
+
+func (r trashListResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, summary := range r {
+		rows = append(rows, []string{summary.Did, summary.State, summary.RemovedAt.Format(time.RFC3339), summary.ExpiresAt.Format(time.RFC3339)})
+	}
+	return []string{"did", "state", "removed at", "expires at"}, rows
+}
+
+// restoreResult is the result of the trash restore command
+type restoreResult struct {
+	Did   string `json:"did"`
+	State string `json:"state"`
+}
+
+func (r restoreResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "The did %s is restored\n", r.Did)
+}
+
+func (r restoreResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "state"}, [][]string{{r.Did, r.State}}
+}
+
+// emptyTrashResult is the result of the trash empty command
+type emptyTrashResult struct {
+	Purged []string `json:"purged"`
+}
+
+func (r emptyTrashResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Deleted %d removed dids\n", len(r.Purged))
+}
+
+func (r emptyTrashResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, did := range r.Purged {
+		rows = append(rows, []string{did})
+	}
+	return []string{"did"}, rows
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemove(t *testing.T) {
+	created := wallet.DidBucket{Did: "did:ebsi:zfEmvX5twhXjQJiCWsukvQA", State: wallet.StateCreated}
+	registered := wallet.DidBucket{Did: "did:ebsi:zrP7S6ZqEn1vWPDMdTyx5jW", State: wallet.StateRegistered}
+	location := newTestWallet(t, created, registered)
+	flags := []string{"--backend", "dir", "--wallet", location, "--output", "json"}
+
+	t.Run("not confirmed", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"remove", "--did", created.Did}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("to trash", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"remove", "--did", created.Did, "--yes"}, flags...)...)
+		require.NoError(t, err)
+		removed := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &removed))
+		assert.Equal(t, false, removed["permanent"])
+		assert.NotEmpty(t, removed["restorableUntil"])
+
+		_, err = executeCommand(t, append([]string{"status", "--did", created.Did}, flags...)...)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+	t.Run("trash list", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"trash", "list"}, flags...)...)
+		require.NoError(t, err)
+		trash := []map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &trash))
+		require.Len(t, trash, 1)
+		assert.Equal(t, created.Did, trash[0]["did"])
+	})
+	t.Run("restore", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"trash", "restore", "--did", created.Did}, flags...)...)
+		require.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"status", "--did", created.Did}, flags...)...)
+		assert.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"trash", "restore", "--did", created.Did}, flags...)...)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+	t.Run("registered", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"remove", "--did", registered.Did, "--yes"}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+		_, err = executeCommand(t, append([]string{"remove", "--did", registered.Did, "--yes", "--force", "--permanent"}, flags...)...)
+		assert.NoError(t, err)
+
+		output, err := executeCommand(t, append([]string{"trash", "list"}, flags...)...)
+		require.NoError(t, err)
+		assert.JSONEq(t, "[]", output)
+	})
+	t.Run("empty", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"remove", "--did", created.Did, "--yes"}, flags...)...)
+		require.NoError(t, err)
+		output, err := executeCommand(t, append([]string{"trash", "empty", "--yes"}, flags...)...)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"purged":["`+created.Did+`"]}`, output)
+	})
+}
@@ -5,8 +5,15 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/gossif/admin/config"
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
 	"github.com/gossif/ebsi"
 	"github.com/spf13/cobra"
 )
@@ -15,22 +22,135 @@ var ResolveCmd = &cobra.Command{
 	Use:   "resolve",
 	Short: "Step 4: Resolve a did document.",
 	Args:  cobra.ExactArgs(0),
-	Run: func(cmd *cobra.Command, _ []string) {
-		didString, _ := cmd.Flags().GetString("did")
-		did := ebsi.NewDecentralizedIdentifier()
-		if err := did.ParseIdentifier(didString); err != nil {
-			fmt.Printf("Identifier is not valid\n'%s'\n", err)
-			return
-		}
-		ebsiTrustList := ebsi.NewEBSITrustList(
-			ebsi.WithBaseUrl("https://api-pilot.ebsi.eu"),
-		)
-		rawdoc, err := ebsiTrustList.ResolveDid(did.String())
-		if err != nil {
-			fmt.Printf("Failed to resolve the did document.\n%s\n", err)
-			return
-		}
-		jsonDiddoc, _ := json.MarshalIndent(rawdoc, "", "    ")
-		fmt.Printf("Resolving the did document succeeded.\n%s\n", string(jsonDiddoc))
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := ebsiDidFlag(cmd)
+		if err != nil {
+			return err
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		rawdoc, err := resolveDocument(env, did)
+		if err != nil {
+			return err
+		}
+		return printResult(cmd, resolveResult{Did: did, Document: rawdoc})
 	},
 }
+
+func init() {
+	ResolveCmd.Flags().StringP("did", "d", "", "the did of the document to resolve")
+}
+
+// resolveResult is the result of the resolve command
+type resolveResult struct {
+	Did      string      `json:"did"`
+	Document interface{} `json:"document"`
+}
+
+func (r resolveResult) writeText(w io.Writer) {
+	jsonDiddoc, _ := json.MarshalIndent(r.Document, "", "    ")
+	fmt.Fprintf(w, "Resolving the did document succeeded.\n%s\n", string(jsonDiddoc))
+}
+
+func (r resolveResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	document, _ := r.Document.(map[string]interface{})
+	verificationMethods, _ := document["verificationMethod"].([]interface{})
+	for _, method := range verificationMethods {
+		verificationMethod, _ := method.(map[string]interface{})
+		rows = append(rows, []string{r.Did, fmt.Sprint(verificationMethod["id"]), fmt.Sprint(verificationMethod["type"]), fmt.Sprint(verificationMethod["controller"])})
+	}
+	return []string{"did", "verification method", "type", "controller"}, rows
+}
+
+// resolveDocument resolves the did document of the did registry
+func resolveDocument(env config.Environment, did string) (interface{}, error) {
+	ebsiTrustList := ebsi.NewEBSITrustList(
+		ebsi.WithBaseUrl(env.BaseUrl),
+		// the http client dumps the requests to stderr, the ebsi package dumps them to stdout
+		ebsi.WithVerbose(false),
+		ebsi.WithHttpClient(env.HttpClient()),
+	)
+	rawdoc, err := ebsiTrustList.ResolveDid(did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the did document: %w", remoteError(err))
+	}
+	return rawdoc, nil
+}
+
+// resolveDid resolves the did document of the did, a did:ebsi is resolved with the did registry, a did:web with
+// the web server of the domain and a did:key from the identifier itself
+func resolveDid(env config.Environment, identifier string) (map[string]interface{}, error) {
+	switch {
+	case did.IsKey(identifier):
+		return did.ResolveKey(identifier)
+	case did.IsWeb(identifier):
+		document, err := did.ResolveWeb(env.HttpClient(), identifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the did document: %w", err)
+		}
+		return document, nil
+	case did.IsEbsi(identifier):
+		rawdoc, err := resolveDocument(env, identifier)
+		if err != nil {
+			return nil, err
+		}
+		documentBytes, err := json.Marshal(rawdoc)
+		if err != nil {
+			return nil, err
+		}
+		document := map[string]interface{}{}
+		if err = json.Unmarshal(documentBytes, &document); err != nil {
+			return nil, fmt.Errorf("invalid did document: %w", err)
+		}
+		return document, nil
+	}
+	return nil, &InvalidDidError{Did: identifier, Err: errors.New("unsupported did method")}
+}
+
+// walletKeyHistory returns the lookup of the retired verification methods of the dids in the wallet. The wallet is
+// opened when a kid isn't in the did document and is only unlocked when it holds the did, a missing wallet isn't
+// created. A did outside the wallet or a wallet that can't be unlocked has no retired methods.
+func walletKeyHistory(cmd *cobra.Command) credential.KeyHistory {
+	return func(kid string) (credential.RetiredKey, bool) {
+		identifier, _, _ := strings.Cut(kid, "#")
+		if opened, err := openExistingWallet(cmd); err != nil || !opened {
+			return credential.RetiredKey{}, false
+		}
+		defer wallet.Close()
+		// the dids are stored in plaintext, the passphrase isn't asked for a did outside the wallet
+		dids, err := wallet.GetAllKeys()
+		if err != nil {
+			return credential.RetiredKey{}, false
+		}
+		inWallet := false
+		for _, did := range dids {
+			inWallet = inWallet || did == identifier
+		}
+		if !inWallet {
+			return credential.RetiredKey{}, false
+		}
+		if initialized, err := wallet.IsInitialized(); err != nil || !initialized {
+			return credential.RetiredKey{}, false
+		}
+		if err := unlockWallet(); err != nil {
+			return credential.RetiredKey{}, false
+		}
+		didBucket, err := wallet.GetBucketByDid(identifier)
+		if err != nil {
+			return credential.RetiredKey{}, false
+		}
+		retiredKey, found := didBucket.RetiredKeyById(kid)
+		if !found {
+			return credential.RetiredKey{}, false
+		}
+		return credential.RetiredKey{
+			Key:        retiredKey.Key,
+			Purposes:   retiredKey.Purposes,
+			ValidFrom:  retiredKey.ValidFrom,
+			ValidUntil: retiredKey.ValidUntil,
+		}, true
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRootCmd returns the root command of the cli with the commands of the package, the flags of the commands
+// are registered next to the commands
+func NewRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "essif",
+		Args:  cobra.ExactArgs(1),
+		Short: "essif - a CLI to manage decentralized identifiers",
+		// errors are printed by Execute, the usage only on invalid flags
+		SilenceErrors:     true,
+		SilenceUsage:      true,
+		PersistentPreRunE: ValidateOutput,
+	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		cmd.PrintErrln(cmd.UsageString())
+		return &UsageError{Err: err}
+	})
+
+	rootCmd.AddCommand(CreateCmd)
+	rootCmd.AddCommand(RegisterCmd)
+	rootCmd.AddCommand(OnboardCmd)
+	rootCmd.AddCommand(AccessTokenCmd)
+	rootCmd.AddCommand(ResolveCmd)
+	rootCmd.AddCommand(ListCmd)
+	rootCmd.AddCommand(StatusCmd)
+	rootCmd.AddCommand(SetupCmd)
+	rootCmd.AddCommand(DocumentCmd)
+	rootCmd.AddCommand(DeactivateCmd)
+	rootCmd.AddCommand(RemoveCmd)
+	rootCmd.AddCommand(TrashCmd)
+	rootCmd.AddCommand(ExportCmd)
+	rootCmd.AddCommand(ImportCmd)
+	rootCmd.AddCommand(KeysCmd)
+	rootCmd.AddCommand(VcCmd)
+	rootCmd.AddCommand(VpCmd)
+	rootCmd.AddCommand(SignCmd)
+	rootCmd.AddCommand(VerifyCmd)
+	rootCmd.AddCommand(WalletCmd)
+	rootCmd.AddCommand(MockServerCmd)
+
+	rootCmd.PersistentFlags().StringP("env", "e", "pilot", "the environment of the configuration (pilot, conformance, production, local).")
+	rootCmd.PersistentFlags().String("config", "", "the configuration file, defaults to $XDG_CONFIG_HOME/essif/config.yaml.")
+	rootCmd.PersistentFlags().String("base-url", "", "the base url of the ebsi apis, overrides the environment.")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose the http requests.")
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "the timeout of the http requests.")
+	rootCmd.PersistentFlags().StringP("wallet", "w", "", "the name or path of the wallet, defaults to the current named wallet.")
+	rootCmd.PersistentFlags().String("backend", "buntdb", "the storage backend of the wallet (buntdb, dir or memory).")
+	rootCmd.PersistentFlags().String("output", "text", "the output format of the results (text, json, yaml or table).")
+	return rootCmd
+}
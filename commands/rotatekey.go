@@ -0,0 +1,209 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/cobra"
+)
+
+var errKeyBoundDid = errors.New("the did is derived from its key, the key can't be rotated")
+
+var DocumentRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Replace a key of the did by a new key, the retired key is kept in the key history.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if didkit.IsKey(did) || didkit.IsEbsiNaturalPerson(did) {
+			return &InvalidDidError{Did: did, Err: errKeyBoundDid}
+		}
+		keyFlag, _ := cmd.Flags().GetString("key")
+		if strings.TrimSpace(keyFlag) == "" {
+			return &UsageError{Err: errors.New("the key is required (issuance, presentation or the key id)")}
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if didBucket.State == wallet.StateDeactivated {
+			return fmt.Errorf("%w: the did %s is deactivated", wallet.ErrInvalidTransition, did)
+		}
+		role, jwkKey, err := bucketKeyByFlag(didBucket, keyFlag)
+		if err != nil {
+			return err
+		}
+		successor, err := generateKeyAsJwk(keyCurve(jwkKey), did)
+		if err != nil {
+			return err
+		}
+		result, rotateErr := rotateBucketKey(env, &didBucket, role, jwkKey, successor)
+		if rotateErr != nil && result.TransactionHashes == nil {
+			return rotateErr
+		}
+		// the ledger is changed, the bucket is saved even when the rotation failed halfway
+		if err = wallet.StoreBucket(didBucket); err != nil {
+			return fmt.Errorf("failed to save the results: %w", err)
+		}
+		if rotateErr != nil {
+			return rotateErr
+		}
+		if didkit.IsWeb(did) {
+			outDir, _ := cmd.Flags().GetString("out")
+			if result.DocumentFile, err = writeWebDocument(outDir, didBucket); err != nil {
+				return fmt.Errorf("failed to write the did document: %w", err)
+			}
+			result.DocumentUrl, _ = didkit.WebDocumentUrl(did)
+		}
+		return printResult(cmd, result)
+	},
+}
+
+func init() {
+	DocumentRotateKeyCmd.Flags().StringP("did", "d", "", "the did of the key to rotate.")
+	DocumentRotateKeyCmd.Flags().StringP("key", "k", "", "the key to rotate (issuance, presentation or the key id).")
+	DocumentRotateKeyCmd.Flags().StringP("out", "o", ".", "the directory to write the did document of the web method to.")
+}
+
+// rotateKeyResult is the result of the document rotate-key command
+type rotateKeyResult struct {
+	Did               string   `json:"did"`
+	Role              string   `json:"role,omitempty"`
+	RetiredKeyId      string   `json:"retiredKeyId"`
+	KeyId             string   `json:"keyId"`
+	KeyType           string   `json:"keyType"`
+	Purposes          []string `json:"purposes"`
+	State             string   `json:"state"`
+	TransactionHashes []string `json:"transactionHashes,omitempty"`
+	DocumentFile      string   `json:"documentFile,omitempty"`
+	DocumentUrl       string   `json:"documentUrl,omitempty"`
+}
+
+func (r rotateKeyResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Rotating the key %s of %s succeeded, the new key is %s\n", r.RetiredKeyId, r.Did, r.KeyId)
+	if r.DocumentFile != "" {
+		fmt.Fprintf(w, "The did document is written to %s, publish it at %s\n", r.DocumentFile, r.DocumentUrl)
+	}
+}
+
+func (r rotateKeyResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "role", "retired key id", "key id", "key type", "purposes", "state"},
+		[][]string{{r.Did, r.Role, r.RetiredKeyId, r.KeyId, r.KeyType, strings.Join(r.Purposes, ","), r.State}}
+}
+
+// bucketKeyByFlag returns the role and the key of the bucket of the flag, the flag is a role or a key id
+func bucketKeyByFlag(didBucket wallet.DidBucket, keyFlag string) (string, jwk.Key, error) {
+	switch {
+	case keyFlag == wallet.KeyRoleIssuance && didBucket.IssuanceKey != nil:
+		return wallet.KeyRoleIssuance, didBucket.IssuanceKey, nil
+	case keyFlag == wallet.KeyRolePresentation && didBucket.PresentationKey != nil:
+		return wallet.KeyRolePresentation, didBucket.PresentationKey, nil
+	case didBucket.IssuanceKey != nil && didBucket.IssuanceKey.KeyID() == keyFlag:
+		return wallet.KeyRoleIssuance, didBucket.IssuanceKey, nil
+	case didBucket.PresentationKey != nil && didBucket.PresentationKey.KeyID() == keyFlag:
+		return wallet.KeyRolePresentation, didBucket.PresentationKey, nil
+	}
+	if verificationKey, found := didBucket.VerificationKeyById(keyFlag); found {
+		return verificationKey.Role, verificationKey.Key, nil
+	}
+	return "", nil, fmt.Errorf("%w: the did %s has no key %s", wallet.ErrNotFound, didBucket.Did, keyFlag)
+}
+
+// rotateBucketKey replaces the key of the role by the successor and retires the key. The verification method
+// is replaced in the document of the bucket, and in the did registry for a registered ebsi did. The transaction
+// hashes of the result are nil when nothing is changed on the ledger.
+func rotateBucketKey(env config.Environment, didBucket *wallet.DidBucket, role string, jwkKey jwk.Key, successor jwk.Key) (rotateKeyResult, error) {
+	result := rotateKeyResult{
+		Did:          didBucket.Did,
+		Role:         role,
+		RetiredKeyId: jwkKey.KeyID(),
+		KeyId:        successor.KeyID(),
+		KeyType:      keyCurve(successor),
+		Purposes:     didkit.VerificationRelationships(didBucket.Document, jwkKey.KeyID()),
+	}
+	inDocument := didkit.HasVerificationMethod(didBucket.Document, jwkKey.KeyID())
+	onLedger := inDocument && didkit.IsEbsi(didBucket.Did) &&
+		(didBucket.State == wallet.StateRegistered || didBucket.State == wallet.StateUpdated)
+	switch {
+	case onLedger:
+		published, err := publishBucketKey(env, didBucket, successor, result.Purposes)
+		if published.TransactionHashes == nil {
+			return result, err
+		}
+		result.TransactionHashes = published.TransactionHashes
+		if err == nil {
+			var transactionHash string
+			transactionHash, err = newEbsiClient(env).RevokeVerificationMethod(didBucket.AccessToken, didBucket.Did, jwkKey.KeyID(), time.Now(), didBucket.AdminTransactionKey)
+			if err != nil {
+				err = fmt.Errorf("failed to revoke the verification method %s: %w", jwkKey.KeyID(), remoteError(err))
+			} else {
+				result.TransactionHashes = append(result.TransactionHashes, transactionHash)
+			}
+		}
+		if changeErr := didBucket.ChangeState(wallet.StateChange{
+			State:           wallet.StateUpdated,
+			TransactionHash: result.TransactionHashes[len(result.TransactionHashes)-1],
+		}); changeErr != nil && err == nil {
+			err = changeErr
+		}
+		if err != nil {
+			// the successor is on the ledger next to the key, it is kept as an added verification key
+			didBucket.VerificationKeys = append(didBucket.VerificationKeys, wallet.VerificationKey{
+				Key:       successor,
+				Purposes:  published.Purposes,
+				ValidFrom: time.Now().UTC(),
+			})
+			result.State = didBucket.State
+			return result, fmt.Errorf("%w, the new key %s is added to the did document", err, successor.KeyID())
+		}
+		didkit.RemoveVerificationMethod(didBucket.Document, jwkKey.KeyID())
+	case inDocument:
+		documentKey, err := documentPublicKey(successor)
+		if err != nil {
+			return result, err
+		}
+		didkit.AddVerificationMethod(didBucket.Document, successor.KeyID(), documentKey, result.Purposes...)
+		didkit.RemoveVerificationMethod(didBucket.Document, jwkKey.KeyID())
+	}
+	if _, err := didBucket.RetireKey(jwkKey, role, result.Purposes); err != nil {
+		return result, err
+	}
+	switch role {
+	case wallet.KeyRoleIssuance:
+		didBucket.IssuanceKey = successor
+	case wallet.KeyRolePresentation:
+		didBucket.PresentationKey = successor
+	default:
+		didBucket.VerificationKeys = append(didBucket.VerificationKeys, wallet.VerificationKey{
+			Key:       successor,
+			Purposes:  result.Purposes,
+			ValidFrom: time.Now().UTC(),
+		})
+	}
+	result.State = didBucket.State
+	return result, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateKey(t *testing.T) {
+	outDir := t.TempDir()
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+
+	created := map[string]interface{}{}
+	require.NoError(t, executeJson(t, &created, append([]string{"create", "--method", "web", "--domain", "example.org", "--out", outDir}, flags...)...))
+	did := created["did"].(string)
+	issuanceKeyId := created["keys"].([]interface{})[0].(map[string]interface{})["id"].(string)
+
+	t.Run("issuance", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"document", "rotate-key", "--did", did, "--key", "issuance", "--out", outDir}, flags...)...)
+		require.NoError(t, err)
+		rotated := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &rotated))
+		assert.Equal(t, issuanceKeyId, rotated["retiredKeyId"])
+		assert.NotEqual(t, issuanceKeyId, rotated["keyId"])
+		assert.Equal(t, []interface{}{"authentication", "assertionMethod"}, rotated["purposes"])
+
+		documentBytes, err := os.ReadFile(rotated["documentFile"].(string))
+		require.NoError(t, err)
+		assert.Contains(t, string(documentBytes), rotated["keyId"])
+		assert.NotContains(t, string(documentBytes), issuanceKeyId)
+	})
+	t.Run("key history", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"document", "keys", "--did", did}, flags...)...)
+		require.NoError(t, err)
+		keys := struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &keys))
+		require.Len(t, keys.Keys, 3)
+		retiredKey := keys.Keys[2]
+		assert.Equal(t, issuanceKeyId, retiredKey["id"])
+		assert.Equal(t, "issuance", retiredKey["role"])
+		assert.NotEmpty(t, retiredKey["validFrom"])
+		assert.NotEmpty(t, retiredKey["validUntil"])
+		assert.Nil(t, keys.Keys[0]["validUntil"])
+		assert.Equal(t, retiredKey["validUntil"], keys.Keys[0]["validFrom"])
+	})
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"document", "rotate-key", "--did", did, "--key", issuanceKeyId}, flags...)...)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+	t.Run("did key", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"document", "rotate-key", "--did", "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf", "--key", "issuance"}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidDid, commands.ExitCode(err))
+	})
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gossif/admin/config"
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var SetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Create, onboard, register and resolve an ebsi did in one go, an unfinished did is resumed.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+		interactive := !nonInteractive && isTerminal()
+		if _, ok := os.LookupEnv("ESSIF_PASSPHRASE"); !ok && !interactive {
+			return fmt.Errorf("failed to unlock the wallet: %w: set the passphrase with ESSIF_PASSPHRASE", wallet.ErrWalletLocked)
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := setupBucket(cmd, interactive)
+		if err != nil {
+			return err
+		}
+		setup := setupResult{Steps: []string{}}
+		if didBucket.State == "" {
+			cmd.PrintErrln("Step 1: Creating the did.")
+			if didBucket, err = newEbsiBucket(); err != nil {
+				return fmt.Errorf("failed to create the did: %w", err)
+			}
+			if err = didBucket.ChangeState(wallet.StateChange{State: wallet.StateCreated}); err != nil {
+				return err
+			}
+			if err = wallet.StoreBucket(didBucket); err != nil {
+				return fmt.Errorf("failed to save the results: %w", err)
+			}
+			setup.Steps = append(setup.Steps, "create")
+		} else {
+			cmd.PrintErrf("Resuming the setup of %s, the did is %s.\n", didBucket.Did, didBucket.State)
+		}
+		setup.Did = didBucket.Did
+		if setup.Steps, err = runSetupSteps(cmd, env, &didBucket, interactive, setup.Steps); err != nil {
+			return err
+		}
+		setup.State = didBucket.State
+		if didBucket.State == wallet.StateDeactivated {
+			return fmt.Errorf("%w: the did %s is deactivated", wallet.ErrInvalidTransition, didBucket.Did)
+		}
+		if didBucket.State != wallet.StateRegistered && didBucket.State != wallet.StateUpdated {
+			cmd.PrintErrf("The setup is stopped, run essif setup --did %s to resume.\n", didBucket.Did)
+			return printResult(cmd, setup)
+		}
+		cmd.PrintErrln("Step 4: Resolving the did document.")
+		if setup.Document, err = resolveDocument(env, didBucket.Did); err != nil {
+			return err
+		}
+		setup.Steps = append(setup.Steps, "resolve")
+		if !sameDocument(setup.Document, didBucket.Document) {
+			return fmt.Errorf("the resolved did document of %s doesn't match the document in the wallet", didBucket.Did)
+		}
+		setup.Verified = true
+		return printResult(cmd, setup)
+	},
+}
+
+func init() {
+	SetupCmd.Flags().StringP("did", "d", "", "the did to resume, defaults to the unfinished did of the wallet.")
+	SetupCmd.Flags().StringP("token", "t", "", "the onboarding token, instead of the prompt.")
+	SetupCmd.Flags().String("token-file", "", "the file with the onboarding token, instead of the prompt.")
+	SetupCmd.Flags().Bool("non-interactive", false, "never prompt, the onboarding token and ESSIF_PASSPHRASE must be set.")
+}
+
+// setupResult is the result of the setup command
+type setupResult struct {
+	Did      string      `json:"did"`
+	State    string      `json:"state"`
+	Steps    []string    `json:"steps"`
+	Verified bool        `json:"verified"`
+	Document interface{} `json:"document,omitempty"`
+}
+
+func (r setupResult) writeText(w io.Writer) {
+	if !r.Verified {
+		fmt.Fprintf(w, "The did %s is %s\n", r.Did, r.State)
+		return
+	}
+	fmt.Fprintf(w, "Setup of did %s succeeded, the registered did document matches the wallet\n", r.Did)
+}
+
+func (r setupResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "state", "steps", "verified"},
+		[][]string{{r.Did, r.State, strings.Join(r.Steps, ","), strconv.FormatBool(r.Verified)}}
+}
+
+// runSetupSteps onboards and registers the did from the state of the bucket, the bucket is saved after every step.
+// The interactive setup asks to continue before every step.
+func runSetupSteps(cmd *cobra.Command, env config.Environment, didBucket *wallet.DidBucket, interactive bool, steps []string) ([]string, error) {
+	for {
+		switch didBucket.State {
+		case wallet.StateCreated:
+			if interactive && !confirmPrompt("Step 2: Onboard the did? You need the token of https://app-pilot.ebsi.eu/users-onboarding/v2/.") {
+				return steps, nil
+			}
+			onboardingToken, err := onboardToken(cmd, interactive)
+			if err != nil {
+				return steps, &UsageError{Err: fmt.Errorf("failed to read the onboarding token: %w", err)}
+			}
+			cmd.PrintErrln("Step 2: Onboarding the did.")
+			if err = onboardBucket(env, didBucket, onboardingToken); err != nil {
+				return steps, err
+			}
+			steps = append(steps, "onboard")
+		case wallet.StateOnboarded:
+			if interactive && !confirmPrompt("Step 3: Register the did document?") {
+				return steps, nil
+			}
+			cmd.PrintErrln("Step 3: Registering the did document.")
+			if err := registerBucket(env, didBucket); err != nil {
+				return steps, err
+			}
+			steps = append(steps, "register")
+		default:
+			return steps, nil
+		}
+		if err := wallet.StoreBucket(*didBucket); err != nil {
+			return steps, fmt.Errorf("failed to save the results: %w", err)
+		}
+	}
+}
+
+// setupBucket returns the bucket of the --did flag or the unfinished ebsi did of the wallet, the bucket
+// is empty when a new did must be created
+func setupBucket(cmd *cobra.Command, interactive bool) (wallet.DidBucket, error) {
+	if cmd.Flags().Changed("did") {
+		did, err := ebsiDidFlag(cmd)
+		if err != nil {
+			return wallet.DidBucket{}, err
+		}
+		if didkit.IsEbsiNaturalPerson(did) {
+			return wallet.DidBucket{}, &InvalidDidError{Did: did, Err: errNaturalPerson}
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return wallet.DidBucket{}, fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		return didBucket, nil
+	}
+	unfinished, err := unfinishedBuckets()
+	if err != nil {
+		return wallet.DidBucket{}, err
+	}
+	switch {
+	case len(unfinished) == 0:
+		return wallet.DidBucket{}, nil
+	case len(unfinished) == 1:
+		return unfinished[0], nil
+	case !interactive:
+		return wallet.DidBucket{}, &UsageError{Err: fmt.Errorf("the wallet has %d unfinished dids, select one with --did", len(unfinished))}
+	}
+	for i, didBucket := range unfinished {
+		cmd.PrintErrf("%d) %s (%s)\n", i+1, didBucket.Did, didBucket.State)
+	}
+	choice := stringPrompt("Please choose the did to resume, or press enter to create a new did.")
+	if choice == "" {
+		return wallet.DidBucket{}, nil
+	}
+	if i, err := strconv.Atoi(choice); err == nil && i > 0 && i <= len(unfinished) {
+		return unfinished[i-1], nil
+	}
+	return wallet.DidBucket{}, &UsageError{Err: errors.New("invalid choice: " + choice)}
+}
+
+// unfinishedBuckets returns the buckets of the ebsi dids of legal entities that are not registered
+func unfinishedBuckets() ([]wallet.DidBucket, error) {
+	identifiers, err := wallet.GetAllKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the wallet: %w", err)
+	}
+	unfinished := []wallet.DidBucket{}
+	for _, did := range identifiers {
+		if didMethod(did) != "ebsi" || didkit.IsEbsiNaturalPerson(did) {
+			continue
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		if didBucket.State == wallet.StateCreated || didBucket.State == wallet.StateOnboarded {
+			unfinished = append(unfinished, didBucket)
+		}
+	}
+	return unfinished, nil
+}
+
+// sameDocument returns true when the resolved document is equal to the document as json
+func sameDocument(resolved interface{}, document map[string]interface{}) bool {
+	var (
+		resolvedJson, documentJson interface{}
+	)
+	resolvedBytes, err := json.Marshal(resolved)
+	if err != nil {
+		return false
+	}
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return false
+	}
+	json.Unmarshal(resolvedBytes, &resolvedJson)
+	json.Unmarshal(documentBytes, &documentJson)
+	return reflect.DeepEqual(resolvedJson, documentJson)
+}
@@ -0,0 +1,306 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/cobra"
+)
+
+// Serializations of the jws
+const (
+	JwsFormatCompact string = "compact"
+	JwsFormatJson    string = "json"
+)
+
+// Keys of the bucket to sign with
+const (
+	SignKeyIssuance     string = "issuance"
+	SignKeyPresentation string = "presentation"
+	SignKeySigning      string = "signing"
+)
+
+var SignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a payload as JWS with a key of the did.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		identifier, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		keyName, _ := cmd.Flags().GetString("key")
+		algName, _ := cmd.Flags().GetString("alg")
+		inFile, _ := cmd.Flags().GetString("in")
+		format, _ := cmd.Flags().GetString("format")
+		outFile, _ := cmd.Flags().GetString("out")
+		allowDeactivated, _ := cmd.Flags().GetBool("allow-deactivated")
+		if inFile == "" {
+			return &UsageError{Err: errors.New("the payload is missing, set the file with --in or - for stdin")}
+		}
+		if format != JwsFormatCompact && format != JwsFormatJson {
+			return &UsageError{Err: fmt.Errorf("unsupported format: %s", format)}
+		}
+		payload, err := readFileOrStdin(inFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the payload: %w", err)
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", identifier, err)
+		}
+		if err = didBucket.CanSign(allowDeactivated); err != nil {
+			return err
+		}
+		key, err := bucketSigningKey(didBucket, keyName)
+		if err != nil {
+			return err
+		}
+		alg, err := credential.SignatureAlgorithm(key)
+		if err != nil {
+			return err
+		}
+		if algName != "" && !strings.EqualFold(algName, alg.String()) {
+			return &UsageError{Err: fmt.Errorf("the %s key of %s signs with %s, not with %s", keyName, identifier, alg, algName)}
+		}
+		headers := jws.NewHeaders()
+		headers.Set(jws.KeyIDKey, key.KeyID())
+		options := []jws.SignOption{jws.WithKey(alg, key, jws.WithProtectedHeaders(headers))}
+		if format == JwsFormatJson {
+			options = append(options, jws.WithJSON())
+		}
+		signed, err := jws.Sign(payload, options...)
+		if err != nil {
+			return fmt.Errorf("failed to sign the payload: %w", err)
+		}
+		if outFile != "" {
+			if err = os.WriteFile(outFile, append(signed, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write the jws: %w", err)
+			}
+		}
+		return printResult(cmd, signResult{Did: identifier, KeyId: key.KeyID(), Algorithm: alg.String(), Format: format, Jws: string(signed), File: outFile})
+	},
+}
+
+var VerifyCmd = &cobra.Command{
+	Use:   "verify <file|->",
+	Short: "Verify a JWS with a key of the wallet, or with the did document of the kid.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identifier, _ := cmd.Flags().GetString("did")
+		keyName, _ := cmd.Flags().GetString("key")
+		signed, err := readFileOrStdin(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read the jws: %w", err)
+		}
+		message, err := jws.Parse(signed)
+		if err != nil {
+			return &UsageError{Err: fmt.Errorf("invalid jws: %w", err)}
+		}
+		if len(message.Signatures()) != 1 {
+			return &UsageError{Err: errors.New("the jws must have a single signature")}
+		}
+		headers := message.Signatures()[0].ProtectedHeaders()
+		verified := verifyResult{KeyId: headers.KeyID(), Algorithm: headers.Algorithm().String()}
+
+		var publicKey jwk.Key
+		if identifier != "" {
+			verified.Source = "wallet"
+			publicKey, err = walletVerificationKey(cmd, identifier, keyName, headers.KeyID())
+		} else {
+			verified.Source = "did document"
+			publicKey, err = resolvedVerificationKey(cmd, headers.KeyID())
+		}
+		if err != nil && keyName == "" {
+			if retiredKey, found, retiredErr := retiredVerificationKey(cmd, headers.KeyID(), message.Payload()); found {
+				verified.Source = "key history"
+				publicKey, err = retiredKey, retiredErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		verified.KeyId = publicKey.KeyID()
+		if err = verifyJws(signed, headers.Algorithm(), publicKey); err != nil {
+			if printErr := printResult(cmd, verified); printErr != nil {
+				return printErr
+			}
+			return fmt.Errorf("%w: %s", errInvalidSignature, err)
+		}
+		verified.Valid = true
+		verified.Payload = string(message.Payload())
+		return printResult(cmd, verified)
+	},
+}
+
+func init() {
+	SignCmd.Flags().StringP("did", "d", "", "the did of the key.")
+	SignCmd.Flags().StringP("key", "k", SignKeyIssuance, "the key to sign with (issuance, presentation, signing).")
+	SignCmd.Flags().String("alg", "", "the signature algorithm (ES256, ES256K), must match the key.")
+	SignCmd.Flags().StringP("in", "i", "", "the file of the payload, - for stdin.")
+	SignCmd.Flags().StringP("format", "f", JwsFormatCompact, "the serialization of the jws (compact, json).")
+	SignCmd.Flags().StringP("out", "o", "", "the file of the jws, defaults to stdout.")
+	SignCmd.Flags().Bool("allow-deactivated", false, "sign with a key of a deactivated did.")
+	VerifyCmd.Flags().StringP("did", "d", "", "verify with the key of the did in the wallet instead of the resolved did document.")
+	VerifyCmd.Flags().StringP("key", "k", "", "the key of the did in the wallet (issuance, presentation, signing), defaults to the key of the kid.")
+}
+
+// bucketSigningKey returns the key of the bucket with the name, issuance, presentation or signing
+func bucketSigningKey(didBucket wallet.DidBucket, keyName string) (jwk.Key, error) {
+	var key jwk.Key
+	switch keyName {
+	case SignKeyIssuance:
+		key = didBucket.IssuanceKey
+	case SignKeyPresentation:
+		key = didBucket.PresentationKey
+	case SignKeySigning:
+		key = didBucket.AdminSigningKey
+	default:
+		return nil, &UsageError{Err: fmt.Errorf("unsupported key: %s, use issuance, presentation or signing", keyName)}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("the did %s has no %s key", didBucket.Did, keyName)
+	}
+	return key, nil
+}
+
+// walletVerificationKey returns the public key of the did in the wallet, the key with the name or else the key
+// of the bucket with the kid
+func walletVerificationKey(cmd *cobra.Command, identifier string, keyName string, kid string) (jwk.Key, error) {
+	if err := openWallet(cmd); err != nil {
+		return nil, err
+	}
+	defer wallet.Close()
+	if err := unlockWallet(); err != nil {
+		return nil, fmt.Errorf("failed to unlock the wallet: %w", err)
+	}
+	didBucket, err := wallet.GetBucketByDid(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the did bucket of %s: %w", identifier, err)
+	}
+	if keyName != "" {
+		key, err := bucketSigningKey(didBucket, keyName)
+		if err != nil {
+			return nil, err
+		}
+		return key.PublicKey()
+	}
+	for _, key := range exportKeys(didBucket) {
+		if key.Id == kid {
+			return key.key.PublicKey()
+		}
+	}
+	return nil, fmt.Errorf("the key %q of the jws isn't a key of %s: %w", kid, identifier, wallet.ErrNotFound)
+}
+
+// resolvedVerificationKey returns the public key of the verification method of the kid in the resolved did document
+func resolvedVerificationKey(cmd *cobra.Command, kid string) (jwk.Key, error) {
+	identifier, _, found := strings.Cut(kid, "#")
+	if !found || identifier == "" {
+		return nil, &UsageError{Err: fmt.Errorf("the kid %q of the jws isn't a verification method of a did, verify with --did", kid)}
+	}
+	env, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	document, err := resolveDid(env, identifier)
+	if err != nil {
+		return nil, err
+	}
+	return did.VerificationMethodKey(document, kid)
+}
+
+// retiredVerificationKey returns the public key of the retired key of the kid in the key history of the wallet,
+// found is false when the kid isn't retired. The payload must be a jwt issued within the validity of the key.
+func retiredVerificationKey(cmd *cobra.Command, kid string, payload []byte) (jwk.Key, bool, error) {
+	retiredKey, found := walletKeyHistory(cmd)(kid)
+	if !found {
+		return nil, false, nil
+	}
+	claims, err := jwt.Parse(payload, jwt.WithVerify(false), jwt.WithValidate(false))
+	if err != nil || claims.IssuedAt().IsZero() {
+		return nil, true, fmt.Errorf("%w: the key %s is retired, the payload has no iat claim to check its validity", errInvalidSignature, kid)
+	}
+	if !retiredKey.ValidAt(claims.IssuedAt()) {
+		return nil, true, fmt.Errorf("%w: the payload is issued at %s, outside the validity of the retired key %s",
+			errInvalidSignature, claims.IssuedAt().UTC().Format(time.RFC3339), kid)
+	}
+	return retiredKey.Key, true, nil
+}
+
+// verifyJws verifies the signature of the jws with the public key, the algorithm of the header must match the key
+func verifyJws(signed []byte, alg jwa.SignatureAlgorithm, publicKey jwk.Key) error {
+	keyAlg, err := credential.SignatureAlgorithm(publicKey)
+	if err != nil {
+		return err
+	}
+	if keyAlg != alg {
+		return fmt.Errorf("the algorithm %s doesn't match the key", alg)
+	}
+	_, err = jws.Verify(signed, jws.WithKey(alg, publicKey))
+	return err
+}
+
+// signResult is the result of the sign command, the text output is the jws
+type signResult struct {
+	Did       string `json:"did"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Format    string `json:"format"`
+	Jws       string `json:"jws"`
+	File      string `json:"file,omitempty"`
+}
+
+func (r signResult) writeText(w io.Writer) {
+	if r.File != "" {
+		fmt.Fprintf(w, "The payload signed with %s is written to %s\n", r.KeyId, r.File)
+		return
+	}
+	fmt.Fprintln(w, r.Jws)
+}
+
+func (r signResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "kid", "alg", "format"}, [][]string{{r.Did, r.KeyId, r.Algorithm, r.Format}}
+}
+
+// verifyResult is the result of the verify command
+type verifyResult struct {
+	Valid     bool   `json:"valid"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Source    string `json:"source"`
+	Payload   string `json:"payload,omitempty"`
+}
+
+func (r verifyResult) writeText(w io.Writer) {
+	if r.Valid {
+		fmt.Fprintf(w, "The %s signature of %s is valid, verified with the %s.\n", r.Algorithm, r.KeyId, r.Source)
+		return
+	}
+	fmt.Fprintf(w, "The %s signature of %s is not valid, verified with the %s.\n", r.Algorithm, r.KeyId, r.Source)
+}
+
+func (r verifyResult) tableRows() ([]string, [][]string) {
+	return []string{"kid", "alg", "source", "valid"}, [][]string{{r.KeyId, r.Algorithm, r.Source, fmt.Sprint(r.Valid)}}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir()}
+	dir := t.TempDir()
+	payloadFile := filepath.Join(dir, "payload.json")
+	compactFile := filepath.Join(dir, "payload.jws")
+	jsonFile := filepath.Join(dir, "payload.json.jws")
+	require.NoError(t, os.WriteFile(payloadFile, []byte(`{"nonce": "n-0S6_WzA2Mj"}`), 0600))
+
+	did := createDid(t, flags, "--method", "key")
+
+	verify := func(args ...string) (map[string]interface{}, error) {
+		output, err := executeCommand(t, append(append([]string{"verify"}, args...), append(flags, "--output", "json")...)...)
+		verified := map[string]interface{}{}
+		if output != "" {
+			require.NoError(t, json.Unmarshal([]byte(output), &verified))
+		}
+		return verified, err
+	}
+
+	t.Run("compact", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--alg", "ES256", "--in", payloadFile, "--out", compactFile}, flags...)...)
+		require.NoError(t, err)
+		verified, err := verify(compactFile)
+		require.NoError(t, err)
+		assert.Equal(t, true, verified["valid"])
+		assert.Equal(t, "did document", verified["source"])
+		assert.Equal(t, `{"nonce": "n-0S6_WzA2Mj"}`, verified["payload"])
+	})
+	t.Run("json", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--key", "presentation", "--format", "json", "--in", payloadFile, "--out", jsonFile}, flags...)...)
+		require.NoError(t, err)
+		signed, err := os.ReadFile(jsonFile)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(signed), "{"))
+		verified, err := verify(jsonFile, "--did", did)
+		require.NoError(t, err)
+		assert.Equal(t, "wallet", verified["source"])
+	})
+	t.Run("tampered", func(t *testing.T) {
+		signed, err := os.ReadFile(compactFile)
+		require.NoError(t, err)
+		parts := strings.Split(strings.TrimSpace(string(signed)), ".")
+		parts[1] = "eyJub25jZSI6ICJyZXBsYXllZCJ9"
+		tamperedFile := filepath.Join(t.TempDir(), "tampered.jws")
+		require.NoError(t, os.WriteFile(tamperedFile, []byte(strings.Join(parts, ".")), 0600))
+		verified, err := verify(tamperedFile, "--did", did, "--key", "issuance")
+		assert.Equal(t, commands.ExitInvalidCredential, commands.ExitCode(err))
+		assert.Equal(t, false, verified["valid"])
+	})
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--alg", "ES256K", "--in", payloadFile}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("unsupported key", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"sign", "--did", did, "--key", "encryption", "--in", payloadFile}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("no payload", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"sign", "--did", did}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+}
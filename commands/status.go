@@ -0,0 +1,108 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the did in the lifecycle and the next step.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		lastChange := didBucket.LastStateChange()
+		status := statusResult{
+			Did:             did,
+			Method:          didMethod(did),
+			State:           didBucket.State,
+			TransactionHash: lastChange.TransactionHash,
+			Response:        lastChange.Response,
+			NextStep:        nextStep(didBucket),
+			History:         didBucket.StateHistory,
+		}
+		if !lastChange.ChangedAt.IsZero() {
+			status.ChangedAt = &lastChange.ChangedAt
+		}
+		return printResult(cmd, status)
+	},
+}
+
+func init() {
+	StatusCmd.Flags().StringP("did", "d", "", "the did to show the state of.")
+}
+
+// statusResult is the result of the status command
+type statusResult struct {
+	Did             string               `json:"did"`
+	Method          string               `json:"method"`
+	State           string               `json:"state"`
+	ChangedAt       *time.Time           `json:"changedAt,omitempty"`
+	TransactionHash string               `json:"transactionHash,omitempty"`
+	Response        string               `json:"response,omitempty"`
+	NextStep        string               `json:"nextStep,omitempty"`
+	History         []wallet.StateChange `json:"history"`
+}
+
+func (r statusResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "The did %s is %s", r.Did, r.State)
+	if r.ChangedAt != nil {
+		fmt.Fprintf(w, " since %s", r.ChangedAt.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintln(w)
+	if r.TransactionHash != "" {
+		fmt.Fprintf(w, "The last transaction is %s\n", r.TransactionHash)
+	}
+	if r.NextStep != "" {
+		fmt.Fprintf(w, "The next step is: essif %s --did %s\n", r.NextStep, r.Did)
+	}
+}
+
+func (r statusResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, change := range r.History {
+		rows = append(rows, []string{r.Did, change.State, change.ChangedAt.Local().Format(time.RFC3339), change.TransactionHash})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{r.Did, r.State, "", ""})
+	}
+	return []string{"did", "state", "changed at", "transaction hash"}, rows
+}
+
+// nextStep returns the command of the next step in the lifecycle, only an ebsi did of a legal entity
+// is onboarded and registered
+func nextStep(didBucket wallet.DidBucket) string {
+	if didMethod(didBucket.Did) != "ebsi" || didkit.IsEbsiNaturalPerson(didBucket.Did) {
+		return ""
+	}
+	switch didBucket.State {
+	case wallet.StateCreated:
+		return "onboard"
+	case wallet.StateOnboarded:
+		return "register"
+	default:
+		return ""
+	}
+}
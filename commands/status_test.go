@@ -0,0 +1,37 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	bucket := wallet.DidBucket{Did: "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"}
+	assert.NoError(t, bucket.ChangeState(wallet.StateChange{State: wallet.StateCreated}))
+	location := newTestWallet(t, bucket)
+
+	t.Run("created", func(t *testing.T) {
+		output, err := executeCommand(t, "status", "--did", bucket.Did, "--backend", "dir", "--wallet", location, "--output", "json")
+		assert.NoError(t, err)
+		status := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(output), &status))
+		assert.Equal(t, wallet.StateCreated, status["state"])
+		assert.Equal(t, "onboard", status["nextStep"])
+		assert.Len(t, status["history"], 1)
+	})
+	t.Run("unknown did", func(t *testing.T) {
+		_, err := executeCommand(t, "status", "--did", "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf", "--backend", "dir", "--wallet", location)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+	t.Run("invalid did", func(t *testing.T) {
+		_, err := executeCommand(t, "status", "--did", "example", "--backend", "dir", "--wallet", location)
+		assert.Equal(t, commands.ExitInvalidDid, commands.ExitCode(err))
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/config"
+	"github.com/gossif/admin/ebsiapi"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+// accessTokenLeeway is the minimal remaining lifetime of a cached access token
+const accessTokenLeeway = time.Minute
+
+var AccessTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Request an access token of the authorisation api for an onboarded did.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		did, err := ebsiDidFlag(cmd)
+		if err != nil {
+			return err
+		}
+		scope, _ := cmd.Flags().GetString("scope")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(did)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", did, err)
+		}
+		cached, err := bucketAccessToken(env, &didBucket, scope, refresh)
+		if err != nil {
+			return err
+		}
+		if !cached {
+			if err = wallet.StoreBucket(didBucket); err != nil {
+				return fmt.Errorf("failed to save the results: %w", err)
+			}
+		}
+		return printResult(cmd, tokenResult{
+			Did:         did,
+			AccessToken: didBucket.AccessToken,
+			Scope:       didBucket.AccessTokenScope,
+			ExpiresAt:   didBucket.AccessTokenExpiry,
+			Cached:      cached,
+		})
+	},
+}
+
+func init() {
+	AccessTokenCmd.Flags().StringP("did", "d", "", "the did of the access token")
+	AccessTokenCmd.Flags().String("scope", "openid did_authn", "the scope of the access token.")
+	AccessTokenCmd.Flags().Bool("refresh", false, "request a new access token, even when the cached access token is valid.")
+}
+
+// tokenResult is the result of the token command
+type tokenResult struct {
+	Did         string    `json:"did"`
+	AccessToken string    `json:"accessToken"`
+	Scope       string    `json:"scope"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Cached      bool      `json:"cached"`
+}
+
+func (r tokenResult) writeText(w io.Writer) {
+	if r.Cached {
+		fmt.Fprintf(w, "Using the cached access token, it expires at %s.\n%s\n", r.ExpiresAt.Local().Format(time.RFC3339), r.AccessToken)
+		return
+	}
+	fmt.Fprintf(w, "Requesting the access token succeeded, it expires at %s.\n%s\n", r.ExpiresAt.Local().Format(time.RFC3339), r.AccessToken)
+}
+
+func (r tokenResult) tableRows() ([]string, [][]string) {
+	return []string{"did", "scope", "expires at", "cached", "access token"},
+		[][]string{{r.Did, r.Scope, r.ExpiresAt.Local().Format(time.RFC3339), fmt.Sprint(r.Cached), r.AccessToken}}
+}
+
+// bucketAccessToken sets the access token of the bucket, the cached access token is used unless it is about to
+// expire or a refresh is requested. It returns true when the cached access token is used.
+func bucketAccessToken(env config.Environment, didBucket *wallet.DidBucket, scope string, refresh bool) (bool, error) {
+	if strings.TrimSpace(didBucket.Token) == "" || didBucket.AdminSigningKey == nil {
+		return false, fmt.Errorf("the did %s is not onboarded", didBucket.Did)
+	}
+	if err := didBucket.CanSign(false); err != nil {
+		return false, err
+	}
+	if !refresh && hasValidAccessToken(*didBucket, scope) {
+		return true, nil
+	}
+	if didBucket.AdminEncryptionKey == nil {
+		didBucket.AdminEncryptionKey, _ = generateSecp256k1AsJwk(didBucket.Did)
+	}
+	accessToken, err := newEbsiClient(env).AccessToken(didBucket.Did, scope, didBucket.Token, didBucket.AdminSigningKey, didBucket.AdminEncryptionKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to request the access token: %w", remoteError(err))
+	}
+	didBucket.AccessToken = accessToken.AccessToken
+	didBucket.AccessTokenScope = accessToken.Scope
+	didBucket.AccessTokenExpiry = accessToken.ExpiresAt
+	return false, nil
+}
+
+// ledgerAccessToken sets the access token of the bucket to change the did document on the ledger. A refreshed access
+// token is saved before the ledger is changed, it is kept when the transaction fails.
+func ledgerAccessToken(env config.Environment, didBucket *wallet.DidBucket) error {
+	cached, err := bucketAccessToken(env, didBucket, ebsiapi.DefaultScope, false)
+	if err != nil {
+		return err
+	}
+	if !cached {
+		if err = wallet.StoreBucket(*didBucket); err != nil {
+			return fmt.Errorf("failed to save the access token: %w", err)
+		}
+	}
+	return nil
+}
+
+// newEbsiClient returns the client of the ebsi apis of the environment
+func newEbsiClient(env config.Environment) *ebsiapi.Client {
+	return ebsiapi.NewClient(
+		ebsiapi.WithBaseUrl(env.BaseUrl),
+		ebsiapi.WithHttpClient(env.HttpClient()),
+	)
+}
+
+// hasValidAccessToken returns true when the cached access token of the scope is not about to expire
+func hasValidAccessToken(didBucket wallet.DidBucket, scope string) bool {
+	if strings.TrimSpace(scope) == "" {
+		scope = ebsiapi.DefaultScope
+	}
+	return didBucket.AccessToken != "" &&
+		didBucket.AccessTokenScope == scope &&
+		time.Until(didBucket.AccessTokenExpiry) > accessTokenLeeway
+}
@@ -0,0 +1,364 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/config"
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var VcCmd = &cobra.Command{
+	Use:   "vc",
+	Short: "Issue and verify verifiable credentials.",
+}
+
+var VcIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue a verifiable credential as JWT signed with the issuance key of the did.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		issuer, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		subject, _ := cmd.Flags().GetString("subject")
+		if strings.TrimSpace(subject) == "" {
+			return &UsageError{Err: errors.New("the subject of the credential is missing, set it with --subject")}
+		}
+		types, _ := cmd.Flags().GetStringSlice("type")
+		claimsFile, _ := cmd.Flags().GetString("claims")
+		validFor, _ := cmd.Flags().GetDuration("expires")
+		schemaId, _ := cmd.Flags().GetString("schema")
+		outFile, _ := cmd.Flags().GetString("out")
+		allowDeactivated, _ := cmd.Flags().GetBool("allow-deactivated")
+
+		claims := map[string]interface{}{}
+		if claimsFile != "" {
+			claimsBytes, err := readFileOrStdin(claimsFile)
+			if err != nil {
+				return fmt.Errorf("failed to read the claims: %w", err)
+			}
+			if err = json.Unmarshal(claimsBytes, &claims); err != nil {
+				return &UsageError{Err: fmt.Errorf("the claims must be a json object: %w", err)}
+			}
+		}
+		opts := []credential.Option{}
+		if validFor > 0 {
+			opts = append(opts, credential.WithExpirationDate(time.Now().Add(validFor)))
+		}
+		if schemaId != "" {
+			opts = append(opts, credential.WithSchema(schemaId))
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(issuer)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", issuer, err)
+		}
+		if err = didBucket.CanSign(allowDeactivated); err != nil {
+			return err
+		}
+		if didBucket.IssuanceKey == nil {
+			return fmt.Errorf("the did %s has no issuance key", issuer)
+		}
+		vc := credential.New(issuer, subject, types, claims, opts...)
+		signed, err := credential.SignJwt(vc, didBucket.IssuanceKey)
+		if err != nil {
+			return fmt.Errorf("failed to sign the credential: %w", err)
+		}
+		if _, err = storeCredential([]byte(signed)); err != nil {
+			return fmt.Errorf("failed to store the credential: %w", err)
+		}
+		if outFile != "" {
+			if err = os.WriteFile(outFile, []byte(signed+"\n"), 0644); err != nil {
+				return fmt.Errorf("failed to write the credential: %w", err)
+			}
+		}
+		issued := vcIssueResult{Id: fmt.Sprint(vc["id"]), Issuer: issuer, Subject: subject, Types: vc["type"], Jwt: signed, File: outFile}
+		if expirationDate, ok := vc["expirationDate"].(string); ok {
+			issued.ExpirationDate = expirationDate
+		}
+		return printResult(cmd, issued)
+	},
+}
+
+var VcVerifyCmd = &cobra.Command{
+	Use:   "verify <file|->",
+	Short: "Verify the signature, the validity and the schema of a verifiable credential JWT.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := readFileOrStdin(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read the credential: %w", err)
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		resolve := func(issuer string) (map[string]interface{}, error) {
+			return resolveDid(env, issuer)
+		}
+		verdict := credential.VerifyJwt(string(token), resolve,
+			credential.WithKeyHistory(walletKeyHistory(cmd)),
+			credential.WithSchemaLoader(func(id string) (map[string]interface{}, error) {
+				return loadSchema(env, id)
+			}),
+		)
+		if err = printResult(cmd, vcVerifyResult{verdict}); err != nil {
+			return err
+		}
+		return verdict.Err()
+	},
+}
+
+var VcStoreCmd = &cobra.Command{
+	Use:   "store <file|->",
+	Short: "Store a received verifiable credential, a JWT or a JSON-LD credential, in the wallet.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readFileOrStdin(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read the credential: %w", err)
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		entry, err := storeCredential(data)
+		if err != nil {
+			return fmt.Errorf("failed to store the credential: %w", err)
+		}
+		return printResult(cmd, vcListResult{Credentials: []wallet.CredentialEntry{entry}})
+	},
+}
+
+var VcListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the verifiable credentials in the wallet.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		filter := wallet.CredentialFilter{}
+		filter.Subject, _ = cmd.Flags().GetString("holder")
+		filter.Issuer, _ = cmd.Flags().GetString("issuer")
+		filter.Type, _ = cmd.Flags().GetString("type")
+		filter.Expired, _ = cmd.Flags().GetBool("expired")
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		entries, err := wallet.Credentials(filter)
+		if err != nil {
+			return fmt.Errorf("failed to list the credentials: %w", err)
+		}
+		return printResult(cmd, vcListResult{Credentials: entries})
+	},
+}
+
+var VcShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a verifiable credential of the wallet, the text output is the credential as stored.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		entry, data, err := wallet.GetCredential(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load the credential %s: %w", args[0], err)
+		}
+		return printResult(cmd, vcShowResult{CredentialEntry: entry, Credential: string(data)})
+	},
+}
+
+func init() {
+	VcCmd.AddCommand(VcIssueCmd)
+	VcCmd.AddCommand(VcVerifyCmd)
+	VcCmd.AddCommand(VcStoreCmd)
+	VcCmd.AddCommand(VcListCmd)
+	VcCmd.AddCommand(VcShowCmd)
+	VcIssueCmd.Flags().StringP("did", "d", "", "the did of the issuer.")
+	VcIssueCmd.Flags().StringP("subject", "s", "", "the did of the credential subject.")
+	VcIssueCmd.Flags().StringSliceP("type", "t", []string{}, "the types of the credential, VerifiableCredential is added.")
+	VcIssueCmd.Flags().StringP("claims", "c", "", "the json file with the claims of the credential subject, - for stdin.")
+	VcIssueCmd.Flags().Duration("expires", 0, "the validity of the credential, f.e. 8760h, the credential doesn't expire by default.")
+	VcIssueCmd.Flags().String("schema", "", "the url of the json schema of the credential.")
+	VcIssueCmd.Flags().StringP("out", "o", "", "the file of the credential, defaults to stdout.")
+	VcIssueCmd.Flags().Bool("allow-deactivated", false, "sign with the issuance key of a deactivated did.")
+	VcListCmd.Flags().String("holder", "", "list the credentials of which the did is the subject.")
+	VcListCmd.Flags().String("issuer", "", "list the credentials issued by the did.")
+	VcListCmd.Flags().StringP("type", "t", "", "list the credentials of the type.")
+	VcListCmd.Flags().Bool("expired", false, "list only the expired credentials.")
+}
+
+// storeCredential stores the credential in the wallet, indexed by the description of the credential
+func storeCredential(data []byte) (wallet.CredentialEntry, error) {
+	summary, err := credential.Summarize(data)
+	if err != nil {
+		return wallet.CredentialEntry{}, &UsageError{Err: err}
+	}
+	entry := wallet.CredentialEntry{Id: summary.Id, Format: summary.Format, Issuer: summary.Issuer, Subject: summary.Subject, Types: summary.Types}
+	if summary.IssuanceDate != "" {
+		if entry.IssuanceDate, err = time.Parse(time.RFC3339, summary.IssuanceDate); err != nil {
+			return wallet.CredentialEntry{}, &UsageError{Err: fmt.Errorf("invalid issuanceDate: %w", err)}
+		}
+	}
+	if summary.ExpirationDate != "" {
+		expirationDate, err := time.Parse(time.RFC3339, summary.ExpirationDate)
+		if err != nil {
+			return wallet.CredentialEntry{}, &UsageError{Err: fmt.Errorf("invalid expirationDate: %w", err)}
+		}
+		entry.ExpirationDate = &expirationDate
+	}
+	return wallet.StoreCredential(entry, []byte(strings.TrimSpace(string(data))))
+}
+
+// loadSchema downloads the json schema with the id
+func loadSchema(env config.Environment, id string) (map[string]interface{}, error) {
+	response, err := env.HttpClient().Get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request_failed: %d %s", response.StatusCode, http.StatusText(response.StatusCode))
+	}
+	schema := map[string]interface{}{}
+	if err = json.NewDecoder(response.Body).Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+	return schema, nil
+}
+
+// vcIssueResult is the result of the vc issue command, the text output is the jwt
+type vcIssueResult struct {
+	Id             string      `json:"id"`
+	Issuer         string      `json:"issuer"`
+	Subject        string      `json:"subject"`
+	Types          interface{} `json:"type"`
+	ExpirationDate string      `json:"expirationDate,omitempty"`
+	Jwt            string      `json:"jwt"`
+	File           string      `json:"file,omitempty"`
+}
+
+func (r vcIssueResult) writeText(w io.Writer) {
+	if r.File != "" {
+		fmt.Fprintf(w, "The credential %s is written to %s\n", r.Id, r.File)
+		return
+	}
+	fmt.Fprintln(w, r.Jwt)
+}
+
+func (r vcIssueResult) tableRows() ([]string, [][]string) {
+	return []string{"id", "issuer", "subject", "expiration date"},
+		[][]string{{r.Id, r.Issuer, r.Subject, r.ExpirationDate}}
+}
+
+// vcVerifyResult is the result of the vc verify command, the verdict with the outcome of every check
+type vcVerifyResult struct {
+	credential.Verdict
+}
+
+func (r vcVerifyResult) writeText(w io.Writer) {
+	if r.Valid {
+		fmt.Fprintf(w, "The credential %s of %s is valid.\n", r.Id, r.Issuer)
+	} else {
+		fmt.Fprintf(w, "The credential %s of %s is not valid.\n", r.Id, r.Issuer)
+	}
+	writeChecks(w, "  ", r.Checks)
+}
+
+// writeChecks writes the outcome of every check with the indent
+func writeChecks(w io.Writer, indent string, checks []credential.Check) {
+	for _, check := range checks {
+		if check.Passed {
+			fmt.Fprintf(w, "%s[ok]   %s\n", indent, check.Name)
+		} else {
+			fmt.Fprintf(w, "%s[fail] %s: %s\n", indent, check.Name, check.Message)
+		}
+	}
+}
+
+func (r vcVerifyResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, check := range r.Checks {
+		rows = append(rows, []string{check.Name, fmt.Sprint(check.Passed), check.Message})
+	}
+	return []string{"check", "passed", "message"}, rows
+}
+
+// vcListResult is the result of the vc list and vc store commands
+type vcListResult struct {
+	Credentials []wallet.CredentialEntry `json:"credentials"`
+}
+
+func (r vcListResult) writeText(w io.Writer) {
+	if len(r.Credentials) == 0 {
+		fmt.Fprintln(w, "No credentials found.")
+		return
+	}
+	now := time.Now()
+	for _, entry := range r.Credentials {
+		fmt.Fprintf(w, "%s %s issued by %s to %s", entry.Id, strings.Join(entry.Types, ","), entry.Issuer, entry.Subject)
+		if entry.Expired(now) {
+			fmt.Fprint(w, " (expired)")
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (r vcListResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, entry := range r.Credentials {
+		rows = append(rows, []string{entry.Id, entry.Format, strings.Join(entry.Types, ","), entry.Issuer, entry.Subject, formatExpirationDate(entry)})
+	}
+	return []string{"id", "format", "type", "issuer", "subject", "expiration date"}, rows
+}
+
+// vcShowResult is the result of the vc show command, the text output is the credential
+type vcShowResult struct {
+	wallet.CredentialEntry
+	Credential string `json:"credential"`
+}
+
+func (r vcShowResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, r.Credential)
+}
+
+func (r vcShowResult) tableRows() ([]string, [][]string) {
+	return []string{"id", "format", "issuer", "subject", "expiration date"},
+		[][]string{{r.Id, r.Format, r.Issuer, r.Subject, formatExpirationDate(r.CredentialEntry)}}
+}
+
+func formatExpirationDate(entry wallet.CredentialEntry) string {
+	if entry.ExpirationDate == nil {
+		return ""
+	}
+	return entry.ExpirationDate.Format(time.RFC3339)
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/commands"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVcIssue(t *testing.T) {
+	outDir := t.TempDir()
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir()}
+	subject := "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf"
+
+	issuer := createDid(t, flags, "--method", "web", "--domain", "example.org", "--out", outDir)
+
+	claimsFile := filepath.Join(t.TempDir(), "claims.json")
+	require.NoError(t, os.WriteFile(claimsFile, []byte(`{"legalName": "Acme"}`), 0600))
+
+	t.Run("issue", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", subject, "--type", "VerifiableAttestation", "--claims", claimsFile, "--expires", "24h"}, flags...)...)
+		require.NoError(t, err)
+		signed := strings.TrimSpace(output)
+
+		documentBytes, err := os.ReadFile(filepath.Join(outDir, ".well-known", "did.json"))
+		require.NoError(t, err)
+		document := struct {
+			VerificationMethod []struct {
+				Id           string          `json:"id"`
+				PublicKeyJwk json.RawMessage `json:"publicKeyJwk"`
+			} `json:"verificationMethod"`
+		}{}
+		require.NoError(t, json.Unmarshal(documentBytes, &document))
+		message, err := jws.Parse([]byte(signed))
+		require.NoError(t, err)
+		assert.Equal(t, document.VerificationMethod[0].Id, message.Signatures()[0].ProtectedHeaders().KeyID())
+
+		publicKey, err := jwk.ParseKey(document.VerificationMethod[0].PublicKeyJwk)
+		require.NoError(t, err)
+		token, err := jwt.Parse([]byte(signed), jwt.WithKey(message.Signatures()[0].ProtectedHeaders().Algorithm(), publicKey))
+		require.NoError(t, err)
+		assert.Equal(t, issuer, token.Issuer())
+		assert.Equal(t, subject, token.Subject())
+		assert.False(t, token.Expiration().IsZero())
+		vc, _ := token.Get("vc")
+		credentialSubject := vc.(map[string]interface{})["credentialSubject"].(map[string]interface{})
+		assert.Equal(t, "Acme", credentialSubject["legalName"])
+		assert.Equal(t, []interface{}{"VerifiableCredential", "VerifiableAttestation"}, vc.(map[string]interface{})["type"])
+	})
+	t.Run("no subject", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("invalid claims", func(t *testing.T) {
+		invalidFile := filepath.Join(t.TempDir(), "claims.json")
+		require.NoError(t, os.WriteFile(invalidFile, []byte(`["legalName"]`), 0600))
+		_, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", subject, "--claims", invalidFile}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("deactivated", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"deactivate", "--did", issuer, "--yes"}, flags...)...)
+		require.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", subject}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+		_, err = executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", subject, "--allow-deactivated"}, flags...)...)
+		assert.NoError(t, err)
+	})
+}
+
+func TestVcVerify(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir()}
+	credentialFile := filepath.Join(t.TempDir(), "vc.jwt")
+
+	issuer := createDid(t, flags, "--method", "key")
+	_, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", issuer, "--expires", "1h", "--out", credentialFile}, flags...)...)
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"vc", "verify", credentialFile, "--output", "json"}, flags...)...)
+		require.NoError(t, err)
+		verdict := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &verdict))
+		assert.Equal(t, true, verdict["valid"])
+		assert.Equal(t, issuer, verdict["issuer"])
+		assert.Len(t, verdict["checks"], 6)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		signed, err := os.ReadFile(credentialFile)
+		require.NoError(t, err)
+		parts := strings.Split(strings.TrimSpace(string(signed)), ".")
+		parts[2] = strings.Repeat("A", len(parts[2]))
+		tamperedFile := filepath.Join(t.TempDir(), "tampered.jwt")
+		require.NoError(t, os.WriteFile(tamperedFile, []byte(strings.Join(parts, ".")), 0600))
+
+		output, err := executeCommand(t, append([]string{"vc", "verify", tamperedFile}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidCredential, commands.ExitCode(err))
+		assert.Contains(t, output, "[fail] signature")
+	})
+	t.Run("unknown kid", func(t *testing.T) {
+		signed, err := os.ReadFile(credentialFile)
+		require.NoError(t, err)
+		parts := strings.Split(strings.TrimSpace(string(signed)), ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"` + issuer + `#retired","typ":"JWT"}`))
+		unknownKidFile := filepath.Join(t.TempDir(), "unknown-kid.jwt")
+		require.NoError(t, os.WriteFile(unknownKidFile, []byte(strings.Join(parts, ".")), 0600))
+
+		// the key history of a wallet that doesn't exist is empty, verifying doesn't create the wallet
+		missingWallet := filepath.Join(t.TempDir(), "missing")
+		output, err := executeCommand(t, "vc", "verify", unknownKidFile, "--backend", "dir", "--wallet", missingWallet)
+		assert.Equal(t, commands.ExitInvalidCredential, commands.ExitCode(err), output)
+		assert.NoDirExists(t, missingWallet)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"vc", "verify", "not-a-file.jwt"}, flags...)...)
+		assert.Error(t, err, output)
+		assert.Equal(t, commands.ExitFailure, commands.ExitCode(err))
+	})
+}
+
+func TestVcStore(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir(), "--output", "json"}
+	holder := "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf"
+
+	issuer := createDid(t, flags, "--method", "key")
+	_, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", holder, "--type", "VerifiableAttestation"}, flags...)...)
+	require.NoError(t, err)
+
+	listCredentials := func(args ...string) []interface{} {
+		output, err := executeCommand(t, append(append([]string{"vc", "list"}, args...), flags...)...)
+		require.NoError(t, err)
+		listed := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &listed))
+		return listed["credentials"].([]interface{})
+	}
+
+	t.Run("store json-ld", func(t *testing.T) {
+		credentialFile := filepath.Join(t.TempDir(), "diploma.json")
+		require.NoError(t, os.WriteFile(credentialFile, []byte(`{
+			"@context": ["https://www.w3.org/2018/credentials/v1"],
+			"id": "urn:uuid:7f6b1c9e-diploma",
+			"type": ["VerifiableCredential", "VerifiableDiploma"],
+			"issuer": "did:web:university.example.org",
+			"issuanceDate": "2020-06-30T00:00:00Z",
+			"expirationDate": "2021-06-30T00:00:00Z",
+			"credentialSubject": {"id": "`+holder+`"}
+		}`), 0600))
+		_, err := executeCommand(t, append([]string{"vc", "store", credentialFile}, flags...)...)
+		require.NoError(t, err)
+	})
+	t.Run("store jwt with exp", func(t *testing.T) {
+		rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(rawKey)
+		require.NoError(t, err)
+		token, err := jwt.NewBuilder().
+			JwtID("urn:uuid:7f6b1c9e-expired").
+			Issuer("did:web:university.example.org").
+			Subject(holder).
+			Expiration(time.Now().Add(-time.Hour)).
+			Claim("vc", map[string]interface{}{"type": []string{"VerifiableCredential", "VerifiableId"}, "credentialSubject": map[string]interface{}{"id": holder}}).
+			Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+		require.NoError(t, err)
+		credentialFile := filepath.Join(t.TempDir(), "expired.jwt")
+		require.NoError(t, os.WriteFile(credentialFile, signed, 0600))
+		_, err = executeCommand(t, append([]string{"vc", "store", credentialFile}, flags...)...)
+		require.NoError(t, err)
+	})
+	t.Run("store invalid", func(t *testing.T) {
+		credentialFile := filepath.Join(t.TempDir(), "invalid.json")
+		require.NoError(t, os.WriteFile(credentialFile, []byte(`{"type": ["Diploma"]}`), 0600))
+		_, err := executeCommand(t, append([]string{"vc", "store", credentialFile}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("list", func(t *testing.T) {
+		assert.Len(t, listCredentials("--holder", holder), 3)
+		assert.Len(t, listCredentials("--issuer", issuer), 1)
+		assert.Len(t, listCredentials("--holder", issuer), 0)
+		diplomas := listCredentials("--type", "VerifiableDiploma")
+		require.Len(t, diplomas, 1)
+		assert.Equal(t, "json-ld", diplomas[0].(map[string]interface{})["format"])
+		expired := []string{}
+		for _, entry := range listCredentials("--expired") {
+			expired = append(expired, entry.(map[string]interface{})["id"].(string))
+		}
+		assert.ElementsMatch(t, []string{"urn:uuid:7f6b1c9e-diploma", "urn:uuid:7f6b1c9e-expired"}, expired)
+	})
+	t.Run("show", func(t *testing.T) {
+		attestations := listCredentials("--type", "VerifiableAttestation")
+		require.Len(t, attestations, 1)
+		output, err := executeCommand(t, "vc", "show", attestations[0].(map[string]interface{})["id"].(string), "--backend", "dir", "--wallet", flags[3])
+		require.NoError(t, err)
+		_, err = jws.Parse([]byte(strings.TrimSpace(output)))
+		assert.NoError(t, err)
+
+		_, err = executeCommand(t, append([]string{"vc", "show", "urn:uuid:unknown"}, flags...)...)
+		assert.Equal(t, commands.ExitNotFound, commands.ExitCode(err))
+	})
+}
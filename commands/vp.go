@@ -0,0 +1,195 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var VpCmd = &cobra.Command{
+	Use:   "vp",
+	Short: "Create and verify verifiable presentations.",
+}
+
+var VpCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a verifiable presentation as JWT signed with the presentation key of the holder.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		holder, err := didFlag(cmd)
+		if err != nil {
+			return err
+		}
+		credentialFiles, _ := cmd.Flags().GetStringSlice("vc")
+		if len(credentialFiles) == 0 {
+			return &UsageError{Err: errors.New("the credentials of the presentation are missing, set them with --vc")}
+		}
+		audience, _ := cmd.Flags().GetString("audience")
+		nonce, _ := cmd.Flags().GetString("nonce")
+		validFor, _ := cmd.Flags().GetDuration("expires")
+		outFile, _ := cmd.Flags().GetString("out")
+		allowDeactivated, _ := cmd.Flags().GetBool("allow-deactivated")
+
+		credentials := []string{}
+		for _, credentialFile := range credentialFiles {
+			credentialBytes, err := readFileOrStdin(credentialFile)
+			if err != nil {
+				return fmt.Errorf("failed to read the credential: %w", err)
+			}
+			credentials = append(credentials, strings.TrimSpace(string(credentialBytes)))
+		}
+		if err := openWallet(cmd); err != nil {
+			return err
+		}
+		defer wallet.Close()
+		if err := unlockWallet(); err != nil {
+			return fmt.Errorf("failed to unlock the wallet: %w", err)
+		}
+		didBucket, err := wallet.GetBucketByDid(holder)
+		if err != nil {
+			return fmt.Errorf("failed to load the did bucket of %s: %w", holder, err)
+		}
+		if err = didBucket.CanSign(allowDeactivated); err != nil {
+			return err
+		}
+		if didBucket.PresentationKey == nil {
+			return fmt.Errorf("the did %s has no presentation key", holder)
+		}
+		if !hasRelationship(didBucket.Document, didBucket.PresentationKey.KeyID(), "authentication") {
+			return fmt.Errorf("the presentation key of %s isn't an authentication method of the did document, its presentations can't be verified", holder)
+		}
+		vp := credential.NewPresentation(holder, credentials)
+		signed, err := credential.SignPresentationJwt(vp, didBucket.PresentationKey, audience, nonce, validFor)
+		if err != nil {
+			return fmt.Errorf("failed to sign the presentation: %w", err)
+		}
+		if outFile != "" {
+			if err = os.WriteFile(outFile, []byte(signed+"\n"), 0644); err != nil {
+				return fmt.Errorf("failed to write the presentation: %w", err)
+			}
+		}
+		return printResult(cmd, vpCreateResult{Id: fmt.Sprint(vp["id"]), Holder: holder, Audience: audience, Nonce: nonce, Credentials: len(credentials), Jwt: signed, File: outFile})
+	},
+}
+
+var VpVerifyCmd = &cobra.Command{
+	Use:   "verify <file|->",
+	Short: "Verify the holder binding, the audience, the nonce and the credentials of a verifiable presentation JWT.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		audience, _ := cmd.Flags().GetString("audience")
+		nonce, _ := cmd.Flags().GetString("nonce")
+		token, err := readFileOrStdin(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read the presentation: %w", err)
+		}
+		env, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		resolve := func(identifier string) (map[string]interface{}, error) {
+			return resolveDid(env, identifier)
+		}
+		verdict := credential.VerifyPresentationJwt(string(token), resolve,
+			credential.WithAudience(audience),
+			credential.WithNonce(nonce),
+			credential.WithKeyHistory(walletKeyHistory(cmd)),
+			credential.WithSchemaLoader(func(id string) (map[string]interface{}, error) {
+				return loadSchema(env, id)
+			}),
+		)
+		if err = printResult(cmd, vpVerifyResult{verdict}); err != nil {
+			return err
+		}
+		return verdict.Err()
+	},
+}
+
+func init() {
+	VpCmd.AddCommand(VpCreateCmd)
+	VpCmd.AddCommand(VpVerifyCmd)
+	VpCreateCmd.Flags().StringP("did", "d", "", "the did of the holder.")
+	VpCreateCmd.Flags().StringSlice("vc", []string{}, "the files of the JWT credentials to present, - for stdin.")
+	VpCreateCmd.Flags().String("audience", "", "the verifier the presentation is addressed to.")
+	VpCreateCmd.Flags().String("nonce", "", "the nonce of the verifier.")
+	VpCreateCmd.Flags().Duration("expires", 0, "the validity of the presentation, f.e. 10m, the presentation doesn't expire by default.")
+	VpCreateCmd.Flags().StringP("out", "o", "", "the file of the presentation, defaults to stdout.")
+	VpCreateCmd.Flags().Bool("allow-deactivated", false, "sign with the presentation key of a deactivated did.")
+	VpVerifyCmd.Flags().String("audience", "", "the audience the presentation must be addressed to.")
+	VpVerifyCmd.Flags().String("nonce", "", "the nonce the presentation must contain.")
+}
+
+// hasRelationship returns true when the verification method has the relationship in the did document
+func hasRelationship(document map[string]interface{}, methodId string, relationship string) bool {
+	for _, methodRelationship := range did.VerificationRelationships(document, methodId) {
+		if methodRelationship == relationship {
+			return true
+		}
+	}
+	return false
+}
+
+// vpCreateResult is the result of the vp create command, the text output is the jwt
+type vpCreateResult struct {
+	Id          string `json:"id"`
+	Holder      string `json:"holder"`
+	Audience    string `json:"audience,omitempty"`
+	Nonce       string `json:"nonce,omitempty"`
+	Credentials int    `json:"credentials"`
+	Jwt         string `json:"jwt"`
+	File        string `json:"file,omitempty"`
+}
+
+func (r vpCreateResult) writeText(w io.Writer) {
+	if r.File != "" {
+		fmt.Fprintf(w, "The presentation %s is written to %s\n", r.Id, r.File)
+		return
+	}
+	fmt.Fprintln(w, r.Jwt)
+}
+
+func (r vpCreateResult) tableRows() ([]string, [][]string) {
+	return []string{"id", "holder", "audience", "credentials"},
+		[][]string{{r.Id, r.Holder, r.Audience, fmt.Sprint(r.Credentials)}}
+}
+
+// vpVerifyResult is the result of the vp verify command, the verdict of the presentation and of its credentials
+type vpVerifyResult struct {
+	credential.PresentationVerdict
+}
+
+func (r vpVerifyResult) writeText(w io.Writer) {
+	if r.Valid {
+		fmt.Fprintf(w, "The presentation %s of %s is valid.\n", r.Id, r.Holder)
+	} else {
+		fmt.Fprintf(w, "The presentation %s of %s is not valid.\n", r.Id, r.Holder)
+	}
+	writeChecks(w, "  ", r.Checks)
+	for _, verdict := range r.Credentials {
+		fmt.Fprintf(w, "  credential %s of %s\n", verdict.Id, verdict.Issuer)
+		writeChecks(w, "    ", verdict.Checks)
+	}
+}
+
+func (r vpVerifyResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, check := range r.Checks {
+		rows = append(rows, []string{r.Id, check.Name, fmt.Sprint(check.Passed), check.Message})
+	}
+	for _, verdict := range r.Credentials {
+		for _, check := range verdict.Checks {
+			rows = append(rows, []string{verdict.Id, check.Name, fmt.Sprint(check.Passed), check.Message})
+		}
+	}
+	return []string{"id", "check", "passed", "message"}, rows
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gossif/admin/commands"
+	didkit "github.com/gossif/admin/did"
+	"github.com/gossif/admin/wallet"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVp(t *testing.T) {
+	flags := []string{"--backend", "dir", "--wallet", t.TempDir()}
+	dir := t.TempDir()
+	credentialFile := filepath.Join(dir, "vc.jwt")
+	presentationFile := filepath.Join(dir, "vp.jwt")
+
+	issuer, holder := createDid(t, flags, "--method", "key"), createDid(t, flags, "--method", "key")
+	_, err := executeCommand(t, append([]string{"vc", "issue", "--did", issuer, "--subject", holder, "--out", credentialFile}, flags...)...)
+	require.NoError(t, err)
+
+	t.Run("create", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"vp", "create", "--did", holder, "--vc", credentialFile, "--vc", credentialFile,
+			"--audience", "https://verifier.example.org", "--nonce", "n-0S6_WzA2Mj", "--expires", "10m", "--out", presentationFile}, flags...)...)
+		require.NoError(t, err)
+	})
+	t.Run("verify", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"vp", "verify", presentationFile, "--audience", "https://verifier.example.org", "--nonce", "n-0S6_WzA2Mj", "--output", "json"}, flags...)...)
+		require.NoError(t, err)
+		verdict := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(output), &verdict))
+		assert.Equal(t, true, verdict["valid"])
+		assert.Equal(t, holder, verdict["holder"])
+		assert.Len(t, verdict["credentials"], 2)
+	})
+	t.Run("wrong nonce", func(t *testing.T) {
+		output, err := executeCommand(t, append([]string{"vp", "verify", presentationFile, "--nonce", "replayed"}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidCredential, commands.ExitCode(err))
+		assert.Contains(t, output, "[fail] nonce")
+	})
+	t.Run("no credentials", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"vp", "create", "--did", holder}, flags...)...)
+		assert.Equal(t, commands.ExitUsage, commands.ExitCode(err))
+	})
+	t.Run("deactivated", func(t *testing.T) {
+		_, err := executeCommand(t, append([]string{"deactivate", "--did", holder, "--yes"}, flags...)...)
+		require.NoError(t, err)
+		_, err = executeCommand(t, append([]string{"vp", "create", "--did", holder, "--vc", credentialFile}, flags...)...)
+		assert.Equal(t, commands.ExitInvalidState, commands.ExitCode(err))
+	})
+}
+
+func TestVpCreateUnpublishedKey(t *testing.T) {
+	holder := "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"
+	newKey := func(kid string) jwk.Key {
+		rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(rawKey)
+		require.NoError(t, err)
+		require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+		return key
+	}
+	issuanceKey, presentationKey := newKey(holder+"#issuance"), newKey(holder+"#presentation")
+	issuancePublicKey, err := issuanceKey.PublicKey()
+	require.NoError(t, err)
+	// the did document of a legal entity created before the presentation key was published
+	location := newTestWallet(t, wallet.DidBucket{
+		Did:             holder,
+		State:           wallet.StateRegistered,
+		Document:        didkit.NewDocument(holder, issuancePublicKey),
+		IssuanceKey:     issuanceKey,
+		PresentationKey: presentationKey,
+	})
+	credentialFile := filepath.Join(t.TempDir(), "vc.jwt")
+	require.NoError(t, os.WriteFile(credentialFile, []byte("eyJhbGciOiJFUzI1NiJ9.e30.c2ln"), 0600))
+
+	_, err = executeCommand(t, "vp", "create", "--did", holder, "--vc", credentialFile, "--backend", "dir", "--wallet", location)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't an authentication method")
+}
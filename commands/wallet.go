@@ -0,0 +1,87 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/cobra"
+)
+
+var WalletCmd = &cobra.Command{
+	Use:   "wallet",
+	Short: "Manage the named wallets.",
+}
+
+var WalletUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the named wallet used by the other commands.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := wallet.UseName(args[0]); err != nil {
+			return fmt.Errorf("failed to select the wallet: %w", err)
+		}
+		return printResult(cmd, walletSummary{Name: args[0], Current: true})
+	},
+}
+
+var WalletListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the named wallets, the current wallet is marked with an asterisk.",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		names, err := wallet.Names()
+		if err != nil {
+			return fmt.Errorf("failed to read the wallets: %w", err)
+		}
+		current, _ := wallet.CurrentName()
+		listed := walletListResult{}
+		for _, name := range names {
+			listed = append(listed, walletSummary{Name: name, Current: name == current})
+		}
+		return printResult(cmd, listed)
+	},
+}
+
+func init() {
+	WalletCmd.AddCommand(WalletUseCmd)
+	WalletCmd.AddCommand(WalletListCmd)
+}
+
+// walletSummary describes a named wallet
+type walletSummary struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+}
+
+func (r walletSummary) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Using wallet %s\n", r.Name)
+}
+
+func (r walletSummary) tableRows() ([]string, [][]string) {
+	return []string{"name", "current"}, [][]string{{r.Name, fmt.Sprint(r.Current)}}
+}
+
+// walletListResult is the result of the wallet list command
+type walletListResult []walletSummary
+
+func (r walletListResult) writeText(w io.Writer) {
+	for _, summary := range r {
+		if summary.Current {
+			fmt.Fprintf(w, "* %s\n", summary.Name)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", summary.Name)
+	}
+}
+
+func (r walletListResult) tableRows() ([]string, [][]string) {
+	rows := [][]string{}
+	for _, summary := range r {
+		rows = append(rows, []string{summary.Name, fmt.Sprint(summary.Current)})
+	}
+	return []string{"name", "current"}, rows
+}
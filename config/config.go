@@ -0,0 +1,167 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	EnvPilot       string = "pilot"
+	EnvConformance string = "conformance"
+	EnvProduction  string = "production"
+	EnvLocal       string = "local"
+)
+
+// Environment holds the settings of a named environment
+type Environment struct {
+	Name    string
+	BaseUrl string
+	Verbose bool
+	Timeout time.Duration
+	Wallet  string
+	Backend string
+}
+
+// HttpClient returns a http client with the timeout of the environment, the requests and responses are dumped
+// to stderr for a verbose environment
+func (e Environment) HttpClient() *http.Client {
+	client := &http.Client{Timeout: e.Timeout}
+	if e.Verbose {
+		client.Transport = &dumpTransport{transport: http.DefaultTransport, out: os.Stderr}
+	}
+	return client
+}
+
+// dumpTransport writes the requests and responses to out, apart from the results on stdout
+type dumpTransport struct {
+	transport http.RoundTripper
+	out       io.Writer
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+		fmt.Fprintf(t.out, "REQUEST:\n%s\n", string(dump))
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if dump, err := httputil.DumpResponse(resp, true); err == nil {
+		fmt.Fprintf(t.out, "RESPONSE:\n%s\n", string(dump))
+	}
+	return resp, nil
+}
+
+// setting is a key of an environment with the flag and environment variable to override it
+type setting struct {
+	key    string
+	flag   string
+	envVar string
+}
+
+var settings = []setting{
+	{key: "baseurl", flag: "base-url", envVar: "ESSIF_BASE_URL"},
+	{key: "verbose", flag: "verbose", envVar: "ESSIF_VERBOSE"},
+	{key: "timeout", flag: "timeout", envVar: "ESSIF_TIMEOUT"},
+	{key: "wallet", flag: "wallet", envVar: "ESSIF_WALLET"},
+	{key: "backend", flag: "backend", envVar: "ESSIF_WALLET_BACKEND"},
+}
+
+// builtinEnvironments are the environments known without a configuration file
+var builtinEnvironments = map[string]map[string]interface{}{
+	EnvPilot:       {"baseurl": "https://api-pilot.ebsi.eu"},
+	EnvConformance: {"baseurl": "https://api-conformance.ebsi.eu"},
+	EnvProduction:  {"baseurl": "https://api.ebsi.eu"},
+	EnvLocal:       {"baseurl": "http://localhost:8080"},
+}
+
+// defaultSettings apply to every environment, unless set by the environment
+var defaultSettings = map[string]interface{}{
+	"verbose": false,
+	"timeout": "30s",
+	"wallet":  "",
+	"backend": wallet.BackendBuntDB,
+}
+
+// Load reads the configuration file and returns the selected environment. The settings are
+// taken in the order of the flags, the environment variables, the environment in the
+// configuration file and the built-in environment.
+func Load(flags *pflag.FlagSet) (Environment, error) {
+	v := viper.New()
+	if err := readConfigFile(v, flags); err != nil {
+		return Environment{}, err
+	}
+	v.SetDefault("env", EnvPilot)
+	v.BindEnv("env", "ESSIF_ENV")
+	if flag := flags.Lookup("env"); flag != nil {
+		v.BindPFlag("env", flag)
+	}
+	name := v.GetString("env")
+	builtin, isBuiltin := builtinEnvironments[name]
+	if !isBuiltin && !v.IsSet("environments."+name) {
+		return Environment{}, fmt.Errorf("unknown environment: %s", name)
+	}
+	for key, value := range defaultSettings {
+		v.SetDefault(key, value)
+	}
+	for key, value := range builtin {
+		v.SetDefault(key, value)
+	}
+	for key, value := range v.GetStringMap("environments." + name) {
+		v.SetDefault(strings.ToLower(key), value)
+	}
+	for _, s := range settings {
+		v.BindEnv(s.key, s.envVar)
+		if flag := flags.Lookup(s.flag); flag != nil {
+			v.BindPFlag(s.key, flag)
+		}
+	}
+	if strings.TrimSpace(v.GetString("baseurl")) == "" {
+		return Environment{}, fmt.Errorf("environment %s has no base url", name)
+	}
+	return Environment{
+		Name:    name,
+		BaseUrl: strings.TrimSuffix(v.GetString("baseurl"), "/"),
+		Verbose: v.GetBool("verbose"),
+		Timeout: v.GetDuration("timeout"),
+		Wallet:  v.GetString("wallet"),
+		Backend: v.GetString("backend"),
+	}, nil
+}
+
+// readConfigFile reads the file of the --config flag or ESSIF_CONFIG, defaults to config.yaml
+// in the configuration directory. Only the explicitly set file must exist.
+func readConfigFile(v *viper.Viper, flags *pflag.FlagSet) error {
+	configFile := os.Getenv("ESSIF_CONFIG")
+	if flag := flags.Lookup("config"); flag != nil && flag.Changed {
+		configFile = flag.Value.String()
+	}
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+		return v.ReadInConfig()
+	}
+	configDir, err := wallet.ConfigDir()
+	if err != nil {
+		return err
+	}
+	v.SetConfigName("config")
+	v.AddConfigPath(configDir)
+	err = v.ReadInConfig()
+	if errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/config"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig string = `
+environments:
+  pilot:
+    verbose: true
+    wallet: acme
+  staging:
+    baseUrl: https://api.staging.example.org/
+    timeout: 5s
+`
+
+func newFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("essif", pflag.ContinueOnError)
+	flags.String("env", "pilot", "")
+	flags.String("config", "", "")
+	flags.String("base-url", "", "")
+	flags.Bool("verbose", false, "")
+	flags.Duration("timeout", 30*time.Second, "")
+	flags.String("wallet", "", "")
+	flags.String("backend", "buntdb", "")
+	return flags
+}
+
+func TestLoad(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("ESSIF_CONFIG", "")
+	t.Setenv("ESSIF_ENV", "")
+
+	t.Run("BuiltinEnvironment", func(t *testing.T) {
+		env, err := config.Load(newFlags())
+		assert.NoError(t, err)
+		assert.Equal(t, config.EnvPilot, env.Name)
+		assert.Equal(t, "https://api-pilot.ebsi.eu", env.BaseUrl)
+		assert.False(t, env.Verbose)
+		assert.Equal(t, 30*time.Second, env.Timeout)
+		assert.Equal(t, "buntdb", env.Backend)
+	})
+	assert.NoError(t, os.MkdirAll(filepath.Join(configHome, "essif"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(configHome, "essif", "config.yaml"), []byte(testConfig), 0600))
+
+	t.Run("ConfigFile", func(t *testing.T) {
+		env, err := config.Load(newFlags())
+		assert.NoError(t, err)
+		assert.Equal(t, "https://api-pilot.ebsi.eu", env.BaseUrl)
+		assert.True(t, env.Verbose)
+		assert.Equal(t, "acme", env.Wallet)
+	})
+	t.Run("CustomEnvironment", func(t *testing.T) {
+		flags := newFlags()
+		flags.Set("env", "staging")
+		env, err := config.Load(flags)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://api.staging.example.org", env.BaseUrl)
+		assert.Equal(t, 5*time.Second, env.Timeout)
+		assert.False(t, env.Verbose)
+	})
+	t.Run("EnvironmentVariables", func(t *testing.T) {
+		t.Setenv("ESSIF_ENV", "local")
+		t.Setenv("ESSIF_TIMEOUT", "1m")
+		env, err := config.Load(newFlags())
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080", env.BaseUrl)
+		assert.Equal(t, time.Minute, env.Timeout)
+	})
+	t.Run("Flags", func(t *testing.T) {
+		t.Setenv("ESSIF_BASE_URL", "https://api.example.org")
+		flags := newFlags()
+		flags.Set("base-url", "http://127.0.0.1:9000")
+		flags.Set("verbose", "false")
+		env, err := config.Load(flags)
+		assert.NoError(t, err)
+		assert.Equal(t, "http://127.0.0.1:9000", env.BaseUrl)
+		assert.False(t, env.Verbose)
+	})
+	t.Run("UnknownEnvironment", func(t *testing.T) {
+		flags := newFlags()
+		flags.Set("env", "unknown")
+		_, err := config.Load(flags)
+		assert.Error(t, err)
+	})
+}
+
+func TestHttpClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"}`))
+	}))
+	defer server.Close()
+	// the dumps are written to stderr, stdout is kept for the results
+	stderr := os.Stderr
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stderr = writer
+	defer func() { os.Stderr = stderr }()
+
+	for _, verbose := range []bool{true, false} {
+		response, err := config.Environment{Verbose: verbose, Timeout: time.Second}.HttpClient().Get(server.URL + "/identifiers")
+		require.NoError(t, err)
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"}`, string(body))
+	}
+	writer.Close()
+	dumped, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(dumped), "REQUEST:\nGET /identifiers"))
+	assert.Equal(t, 1, strings.Count(string(dumped), "RESPONSE:\nHTTP/1.1 200 OK"))
+}
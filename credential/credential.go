@@ -0,0 +1,154 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	ContextCredentials       string = "https://www.w3.org/2018/credentials/v1"
+	TypeVerifiableCredential string = "VerifiableCredential"
+	// TypeJsonSchema is the type of the credential schema, see https://www.w3.org/TR/vc-json-schemas/
+	TypeJsonSchema string = "JsonSchemaValidator2018"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported key")
+
+// options are the optional properties of the credential
+type options struct {
+	id             string
+	issuanceDate   time.Time
+	expirationDate time.Time
+	schemaId       string
+}
+
+type Option func(*options)
+
+// WithId sets the id of the credential, defaults to a random urn:uuid
+func WithId(id string) Option {
+	return func(o *options) {
+		o.id = id
+	}
+}
+
+// WithIssuanceDate sets the issuance date, defaults to now
+func WithIssuanceDate(issuanceDate time.Time) Option {
+	return func(o *options) {
+		o.issuanceDate = issuanceDate
+	}
+}
+
+// WithExpirationDate sets the expiration date, the credential doesn't expire by default
+func WithExpirationDate(expirationDate time.Time) Option {
+	return func(o *options) {
+		o.expirationDate = expirationDate
+	}
+}
+
+// WithSchema sets the json schema of the credential subject
+func WithSchema(schemaId string) Option {
+	return func(o *options) {
+		o.schemaId = schemaId
+	}
+}
+
+// New returns a credential of the W3C verifiable credentials data model, the claims are the properties of the
+// credential subject. The type VerifiableCredential is added to the types.
+// See https://www.w3.org/TR/vc-data-model/
+func New(issuer string, subject string, types []string, claims map[string]interface{}, opts ...Option) map[string]interface{} {
+	o := options{id: "urn:uuid:" + uuid.NewString(), issuanceDate: time.Now().UTC()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	credentialTypes := []interface{}{TypeVerifiableCredential}
+	for _, credentialType := range types {
+		if credentialType != TypeVerifiableCredential {
+			credentialTypes = append(credentialTypes, credentialType)
+		}
+	}
+	credentialSubject := map[string]interface{}{}
+	for name, value := range claims {
+		credentialSubject[name] = value
+	}
+	credentialSubject["id"] = subject
+	credential := map[string]interface{}{
+		"@context":          []interface{}{ContextCredentials},
+		"id":                o.id,
+		"type":              credentialTypes,
+		"issuer":            issuer,
+		"issuanceDate":      o.issuanceDate.Format(time.RFC3339),
+		"credentialSubject": credentialSubject,
+	}
+	if !o.expirationDate.IsZero() {
+		credential["expirationDate"] = o.expirationDate.UTC().Format(time.RFC3339)
+	}
+	if o.schemaId != "" {
+		credential["credentialSchema"] = map[string]interface{}{"id": o.schemaId, "type": TypeJsonSchema}
+	}
+	return credential
+}
+
+// SignatureAlgorithm returns the signature algorithm of the elliptic curve key, ES256 for P-256 and ES256K for
+// secp256k1
+func SignatureAlgorithm(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	crv, _ := key.Get(jwk.ECDSACrvKey)
+	switch crv {
+	case jwa.P256:
+		return jwa.ES256, nil
+	case jwa.EllipticCurveAlgorithm(did.KeyTypeSecp256k1):
+		return jwa.ES256K, nil
+	default:
+		return "", fmt.Errorf("%w: %s %v", ErrUnsupportedKey, key.KeyType(), crv)
+	}
+}
+
+// SignJwt returns the credential as JWT signed with the key, the kid of the key is the verification method of
+// the issuer. The registered claims are derived from the credential.
+// See https://www.w3.org/TR/vc-data-model/#json-web-token
+func SignJwt(credential map[string]interface{}, key jwk.Key) (string, error) {
+	alg, err := SignatureAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+	builder := jwt.NewBuilder().
+		Issuer(fmt.Sprint(credential["issuer"])).
+		IssuedAt(time.Now()).
+		Claim("vc", credential)
+	if id, ok := credential["id"].(string); ok {
+		builder.JwtID(id)
+	}
+	if credentialSubject, ok := credential["credentialSubject"].(map[string]interface{}); ok {
+		if subject, ok := credentialSubject["id"].(string); ok {
+			builder.Subject(subject)
+		}
+	}
+	if issuanceDate, err := time.Parse(time.RFC3339, fmt.Sprint(credential["issuanceDate"])); err == nil {
+		builder.NotBefore(issuanceDate)
+	}
+	if expirationDate, err := time.Parse(time.RFC3339, fmt.Sprint(credential["expirationDate"])); err == nil {
+		builder.Expiration(expirationDate)
+	}
+	token, err := builder.Build()
+	if err != nil {
+		return "", err
+	}
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, key.KeyID())
+	headers.Set(jws.TypeKey, "JWT")
+	signed, err := jwt.Sign(token, jwt.WithKey(alg, key, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
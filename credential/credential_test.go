@@ -0,0 +1,120 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/credential"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer  string = "did:web:example.org"
+	testSubject string = "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf"
+)
+
+// generateKey generates a P-256 key of the issuer
+func generateKey(t *testing.T) jwk.Key {
+	t.Helper()
+	rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(rawKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, testIssuer+"#key-1"))
+	return key
+}
+
+func TestCredential(t *testing.T) {
+	t.Run("New", func(t *testing.T) {
+		expirationDate := time.Now().Add(time.Hour)
+		vc := credential.New(testIssuer, testSubject, []string{"VerifiableAttestation", credential.TypeVerifiableCredential},
+			map[string]interface{}{"name": "Acme", "id": "ignored"},
+			credential.WithExpirationDate(expirationDate),
+			credential.WithSchema("https://example.org/schema.json"),
+		)
+		assert.Equal(t, []interface{}{credential.TypeVerifiableCredential, "VerifiableAttestation"}, vc["type"])
+		assert.Equal(t, map[string]interface{}{"id": testSubject, "name": "Acme"}, vc["credentialSubject"])
+		assert.Equal(t, expirationDate.UTC().Format(time.RFC3339), vc["expirationDate"])
+		assert.Contains(t, vc["id"], "urn:uuid:")
+		assert.Equal(t, "https://example.org/schema.json", vc["credentialSchema"].(map[string]interface{})["id"])
+	})
+	t.Run("SignJwt", func(t *testing.T) {
+		key := generateKey(t)
+		vc := credential.New(testIssuer, testSubject, nil, nil, credential.WithId("urn:uuid:1234"), credential.WithExpirationDate(time.Now().Add(time.Hour)))
+		signed, err := credential.SignJwt(vc, key)
+		require.NoError(t, err)
+
+		message, err := jws.Parse([]byte(signed))
+		require.NoError(t, err)
+		assert.Equal(t, key.KeyID(), message.Signatures()[0].ProtectedHeaders().KeyID())
+
+		publicKey, _ := key.PublicKey()
+		token, err := jwt.Parse([]byte(signed), jwt.WithKey(jwa.ES256, publicKey))
+		require.NoError(t, err)
+		assert.Equal(t, testIssuer, token.Issuer())
+		assert.Equal(t, testSubject, token.Subject())
+		assert.Equal(t, "urn:uuid:1234", token.JwtID())
+		assert.False(t, token.Expiration().IsZero())
+		_, found := token.Get("vc")
+		assert.True(t, found)
+	})
+	t.Run("UnsupportedKey", func(t *testing.T) {
+		rawKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		key, err := jwk.FromRaw(rawKey)
+		require.NoError(t, err)
+		_, err = credential.SignJwt(credential.New(testIssuer, testSubject, nil, nil), key)
+		assert.ErrorIs(t, err, credential.ErrUnsupportedKey)
+	})
+	t.Run("Summarize", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, []string{"VerifiableAttestation"}, nil, credential.WithId("urn:uuid:1"))
+		signed, err := credential.SignJwt(vc, generateKey(t))
+		require.NoError(t, err)
+		summary, err := credential.Summarize([]byte(signed))
+		require.NoError(t, err)
+		assert.Equal(t, credential.FormatJwt, summary.Format)
+		assert.Equal(t, "urn:uuid:1", summary.Id)
+		assert.Equal(t, testSubject, summary.Subject)
+		assert.Equal(t, []string{credential.TypeVerifiableCredential, "VerifiableAttestation"}, summary.Types)
+
+		vcBytes, err := json.Marshal(vc)
+		require.NoError(t, err)
+		summary, err = credential.Summarize(vcBytes)
+		require.NoError(t, err)
+		assert.Equal(t, credential.FormatJsonLd, summary.Format)
+		assert.Equal(t, testIssuer, summary.Issuer)
+
+		_, err = credential.Summarize([]byte(`{"type": ["VerifiableCredential"]}`))
+		assert.ErrorIs(t, err, credential.ErrInvalidCredential)
+	})
+	t.Run("SummarizeRegisteredClaims", func(t *testing.T) {
+		// the dates of a jwt without issuanceDate and expirationDate are the nbf and exp claims
+		notBefore := time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC)
+		token, err := jwt.NewBuilder().
+			Issuer(testIssuer).
+			NotBefore(notBefore).
+			Expiration(notBefore.AddDate(1, 0, 0)).
+			Claim("vc", map[string]interface{}{"type": []string{credential.TypeVerifiableCredential}, "credentialSubject": map[string]interface{}{"id": testSubject}}).
+			Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, generateKey(t)))
+		require.NoError(t, err)
+		summary, err := credential.Summarize(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "2020-06-30T00:00:00Z", summary.IssuanceDate)
+		assert.Equal(t, "2021-06-30T00:00:00Z", summary.ExpirationDate)
+	})
+}
@@ -0,0 +1,261 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const TypeVerifiablePresentation string = "VerifiablePresentation"
+
+// Names of the checks of the verification of a presentation
+const (
+	CheckHolder      string = "holder"
+	CheckAudience    string = "audience"
+	CheckNonce       string = "nonce"
+	CheckCredentials string = "credentials"
+)
+
+var ErrInvalidPresentation = errors.New("invalid presentation")
+
+// NewPresentation returns a presentation of the holder with the credentials, the credentials are JWTs
+// See https://www.w3.org/TR/vc-data-model/#presentations-0
+func NewPresentation(holder string, credentials []string) map[string]interface{} {
+	verifiableCredentials := []interface{}{}
+	for _, credential := range credentials {
+		verifiableCredentials = append(verifiableCredentials, strings.TrimSpace(credential))
+	}
+	return map[string]interface{}{
+		"@context":             []interface{}{ContextCredentials},
+		"id":                   "urn:uuid:" + uuid.NewString(),
+		"type":                 []interface{}{TypeVerifiablePresentation},
+		"holder":               holder,
+		"verifiableCredential": verifiableCredentials,
+	}
+}
+
+// SignPresentationJwt returns the presentation as JWT signed with the key of the holder. The audience and the
+// nonce bind the presentation to the verifier, the presentation expires after validFor when it is positive.
+// See https://www.w3.org/TR/vc-data-model/#json-web-token
+func SignPresentationJwt(presentation map[string]interface{}, key jwk.Key, audience string, nonce string, validFor time.Duration) (string, error) {
+	alg, err := SignatureAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	builder := jwt.NewBuilder().
+		Issuer(fmt.Sprint(presentation["holder"])).
+		IssuedAt(now).
+		NotBefore(now).
+		Claim("vp", presentation)
+	if id, ok := presentation["id"].(string); ok {
+		builder.JwtID(id)
+	}
+	if audience != "" {
+		builder.Audience([]string{audience})
+	}
+	if nonce != "" {
+		builder.Claim("nonce", nonce)
+	}
+	if validFor > 0 {
+		builder.Expiration(now.Add(validFor))
+	}
+	token, err := builder.Build()
+	if err != nil {
+		return "", err
+	}
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, key.KeyID())
+	headers.Set(jws.TypeKey, "JWT")
+	signed, err := jwt.Sign(token, jwt.WithKey(alg, key, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// PresentationVerdict is the outcome of the verification of a presentation and of its credentials, the
+// presentation is valid when all the checks passed
+type PresentationVerdict struct {
+	Valid       bool      `json:"valid"`
+	Id          string    `json:"id,omitempty"`
+	Holder      string    `json:"holder,omitempty"`
+	KeyId       string    `json:"kid,omitempty"`
+	Audience    []string  `json:"audience,omitempty"`
+	Nonce       string    `json:"nonce,omitempty"`
+	Checks      []Check   `json:"checks"`
+	Credentials []Verdict `json:"credentials"`
+}
+
+// Err returns ErrInvalidPresentation with the failed checks, nil for a valid presentation
+func (v PresentationVerdict) Err() error {
+	if v.Valid {
+		return nil
+	}
+	failed := []string{}
+	for _, check := range v.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name+": "+check.Message)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidPresentation, strings.Join(failed, ", "))
+}
+
+func (v *PresentationVerdict) check(name string, err error) bool {
+	if err != nil {
+		v.Checks = append(v.Checks, Check{Name: name, Message: err.Error()})
+		v.Valid = false
+		return false
+	}
+	v.Checks = append(v.Checks, Check{Name: name, Passed: true})
+	return true
+}
+
+// VerifyPresentationJwt verifies the presentation of the JWT and every credential of the presentation. The holder
+// did is resolved to select the verification method of the kid, the method must be an authentication method of
+// the holder. The subject of every credential must be the holder. The audience and the nonce are checked when
+// they are set with WithAudience and WithNonce.
+func VerifyPresentationJwt(token string, resolve Resolver, opts ...VerifyOption) PresentationVerdict {
+	o := newVerifyOptions(opts)
+	verdict := PresentationVerdict{Valid: true, Credentials: []Verdict{}}
+	token = strings.TrimSpace(token)
+
+	// format
+	headers, claims, presentation, err := verdict.parse(token)
+	if !verdict.check(CheckFormat, err) {
+		return verdict
+	}
+
+	// holder
+	document, err := resolve(verdict.Holder)
+	if !verdict.check(CheckHolder, err) {
+		return verdict
+	}
+
+	// verification method, the holder binding
+	publicKey, err := methodKey(document, verdict.Holder, verdict.KeyId, "authentication")
+	if err != nil {
+		if retiredKey, found, retiredErr := retiredMethodKey(o.keyHistory, verdict.Holder, verdict.KeyId, "authentication", claims); found {
+			publicKey, err = retiredKey, retiredErr
+		}
+	}
+	if !verdict.check(CheckVerificationMethod, err) {
+		return verdict
+	}
+
+	// signature
+	if !verdict.check(CheckSignature, verifySignature(token, headers.Algorithm(), publicKey)) {
+		return verdict
+	}
+
+	verdict.check(CheckValidity, validateTimeClaims(claims, o))
+	if o.audience != "" {
+		verdict.check(CheckAudience, matchAudience(claims, o.audience))
+	}
+	if o.nonce != "" {
+		verdict.check(CheckNonce, matchNonce(verdict.Nonce, o.nonce))
+	}
+	verdict.check(CheckCredentials, verdict.verifyCredentials(presentation, resolve, opts))
+	return verdict
+}
+
+// parse parses the jwt and describes the presentation of the vp claim, the signature isn't verified
+func (v *PresentationVerdict) parse(token string) (jws.Headers, jwt.Token, map[string]interface{}, error) {
+	message, err := jws.Parse([]byte(token))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(message.Signatures()) != 1 {
+		return nil, nil, nil, errors.New("the jwt must have a single signature")
+	}
+	headers := message.Signatures()[0].ProtectedHeaders()
+	v.KeyId = headers.KeyID()
+	claims, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	presentation, err := jsonClaim(claims, "vp")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	v.Id, _ = presentation["id"].(string)
+	v.Holder, _ = presentation["holder"].(string)
+	v.Audience = claims.Audience()
+	if nonce, found := claims.Get("nonce"); found {
+		v.Nonce = fmt.Sprint(nonce)
+	}
+	if claims.JwtID() != "" {
+		v.Id = claims.JwtID()
+	}
+	switch {
+	case v.Holder == "":
+		v.Holder = claims.Issuer()
+	case claims.Issuer() != "" && claims.Issuer() != v.Holder:
+		return nil, nil, nil, fmt.Errorf("the iss claim %s doesn't match the holder %s", claims.Issuer(), v.Holder)
+	}
+	if v.Holder == "" {
+		return nil, nil, nil, errors.New("the presentation has no holder")
+	}
+	types, _ := presentation["type"].([]interface{})
+	if !containsType(types, TypeVerifiablePresentation) {
+		return nil, nil, nil, fmt.Errorf("the presentation must be of type %s", TypeVerifiablePresentation)
+	}
+	return headers, claims, presentation, nil
+}
+
+// verifyCredentials verifies the credentials of the presentation, the subject of the credentials must be the holder
+func (v *PresentationVerdict) verifyCredentials(presentation map[string]interface{}, resolve Resolver, opts []VerifyOption) error {
+	credentials, ok := presentation["verifiableCredential"].([]interface{})
+	if !ok {
+		if credential, found := presentation["verifiableCredential"]; found {
+			credentials = []interface{}{credential}
+		}
+	}
+	failed := []string{}
+	for i, credential := range credentials {
+		token, ok := credential.(string)
+		if !ok {
+			failed = append(failed, fmt.Sprintf("the credential %d isn't a jwt", i))
+			continue
+		}
+		credentialVerdict := VerifyJwt(token, resolve, opts...)
+		v.Credentials = append(v.Credentials, credentialVerdict)
+		switch {
+		case !credentialVerdict.Valid:
+			failed = append(failed, fmt.Sprintf("the credential %s isn't valid", credentialVerdict.Id))
+		case credentialVerdict.Subject != v.Holder:
+			failed = append(failed, fmt.Sprintf("the subject %s of the credential %s isn't the holder", credentialVerdict.Subject, credentialVerdict.Id))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// matchAudience checks that the presentation is addressed to the audience
+func matchAudience(claims jwt.Token, audience string) error {
+	for _, aud := range claims.Audience() {
+		if aud == audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("the presentation isn't addressed to %s", audience)
+}
+
+// matchNonce checks the nonce of the presentation
+func matchNonce(nonce string, expected string) error {
+	if nonce != expected {
+		return fmt.Errorf("the nonce %q doesn't match %q", nonce, expected)
+	}
+	return nil
+}
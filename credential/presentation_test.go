@@ -0,0 +1,87 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHolder string = "did:web:holder.example.org"
+
+func TestPresentation(t *testing.T) {
+	issuerKey := generateKey(t)
+	issuerPublicKey, err := issuerKey.PublicKey()
+	require.NoError(t, err)
+	holderKey := generateKey(t)
+	require.NoError(t, holderKey.Set(jwk.KeyIDKey, testHolder+"#key-1"))
+	holderPublicKey, err := holderKey.PublicKey()
+	require.NoError(t, err)
+	documents := map[string]map[string]interface{}{
+		testIssuer: did.NewDocument(testIssuer, issuerPublicKey),
+		testHolder: did.NewDocument(testHolder, holderPublicKey),
+	}
+	resolve := func(identifier string) (map[string]interface{}, error) {
+		if document, found := documents[identifier]; found {
+			return document, nil
+		}
+		return nil, errors.New("not found")
+	}
+	issue := func(subject string) string {
+		signed, err := credential.SignJwt(credential.New(testIssuer, subject, []string{"VerifiableAttestation"}, nil), issuerKey)
+		require.NoError(t, err)
+		return signed
+	}
+	present := func(key jwk.Key, credentials ...string) string {
+		signed, err := credential.SignPresentationJwt(credential.NewPresentation(testHolder, credentials), key, "https://verifier.example.org", "n-0S6_WzA2Mj", time.Minute)
+		require.NoError(t, err)
+		return signed
+	}
+	presentationCheck := func(verdict credential.PresentationVerdict) string {
+		for _, check := range verdict.Checks {
+			if !check.Passed {
+				return check.Name
+			}
+		}
+		return ""
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		verdict := credential.VerifyPresentationJwt(present(holderKey, issue(testHolder), issue(testHolder)), resolve,
+			credential.WithAudience("https://verifier.example.org"), credential.WithNonce("n-0S6_WzA2Mj"))
+		assert.True(t, verdict.Valid, verdict.Checks)
+		assert.NoError(t, verdict.Err())
+		assert.Equal(t, testHolder, verdict.Holder)
+		assert.Len(t, verdict.Credentials, 2)
+		assert.Len(t, verdict.Checks, 8)
+	})
+	t.Run("HolderBinding", func(t *testing.T) {
+		verdict := credential.VerifyPresentationJwt(present(issuerKey, issue(testHolder)), resolve)
+		assert.Equal(t, credential.CheckVerificationMethod, presentationCheck(verdict))
+		assert.ErrorIs(t, verdict.Err(), credential.ErrInvalidPresentation)
+	})
+	t.Run("Audience", func(t *testing.T) {
+		verdict := credential.VerifyPresentationJwt(present(holderKey, issue(testHolder)), resolve, credential.WithAudience("https://other.example.org"))
+		assert.Equal(t, credential.CheckAudience, presentationCheck(verdict))
+	})
+	t.Run("Nonce", func(t *testing.T) {
+		verdict := credential.VerifyPresentationJwt(present(holderKey, issue(testHolder)), resolve, credential.WithNonce("replayed"))
+		assert.Equal(t, credential.CheckNonce, presentationCheck(verdict))
+	})
+	t.Run("Credentials", func(t *testing.T) {
+		verdict := credential.VerifyPresentationJwt(present(holderKey, issue(testSubject)), resolve)
+		assert.Equal(t, credential.CheckCredentials, presentationCheck(verdict))
+
+		verdict = credential.VerifyPresentationJwt(present(holderKey, "not a jwt"), resolve)
+		assert.Equal(t, credential.CheckCredentials, presentationCheck(verdict))
+		assert.False(t, verdict.Credentials[0].Valid)
+	})
+}
@@ -0,0 +1,424 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUncheckedSchema is the error of a schema that can't be checked, f.e. a schema with an unsupported keyword
+var ErrUncheckedSchema = errors.New("the schema can't be checked")
+
+// maxSchemaDepth limits the nesting of the $refs, a schema that refers to itself recursively fails
+const maxSchemaDepth int = 32
+
+// annotationKeywords are the keywords of a schema that don't constrain the value
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true, "title": true,
+	"description": true, "default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// validationKeywords are the keywords of a schema that are validated
+var validationKeywords = map[string]bool{
+	"$ref": true, "type": true, "enum": true, "const": true, "allOf": true, "anyOf": true, "oneOf": true, "not": true,
+	"required": true, "properties": true, "patternProperties": true, "additionalProperties": true,
+	"minProperties": true, "maxProperties": true, "items": true, "minItems": true, "maxItems": true,
+	"uniqueItems": true, "contains": true, "minLength": true, "maxLength": true, "pattern": true, "format": true,
+	"minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true, "multipleOf": true,
+}
+
+// ValidateSchema validates the value against the json schema. The keywords of validationKeywords are validated,
+// a schema with another keyword fails instead of passing unchecked. A $ref within the schema is resolved against
+// the schema, another $ref is loaded with the loader; without a loader it fails.
+func ValidateSchema(schema map[string]interface{}, value interface{}, loader SchemaLoader) error {
+	validator := &schemaValidator{loader: loader, loaded: map[string]map[string]interface{}{}}
+	return validator.validate(schema, schema, value, "$", 0)
+}
+
+// schemaValidator validates the values against a schema, the loaded schemas of the $refs are cached
+type schemaValidator struct {
+	loader SchemaLoader
+	loaded map[string]map[string]interface{}
+}
+
+// validate validates the value against the schema, root is the schema of the local $refs
+func (v *schemaValidator) validate(schema map[string]interface{}, root map[string]interface{}, value interface{}, path string, depth int) error {
+	if depth > maxSchemaDepth {
+		return uncheckedSchema(path, "the $refs are nested too deep")
+	}
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if !annotationKeywords[keyword] && !validationKeywords[keyword] {
+			return uncheckedSchema(path, "the keyword %s isn't supported", keyword)
+		}
+	}
+	if ref, found := schema["$ref"].(string); found {
+		refSchema, refRoot, err := v.resolve(ref, root)
+		if err != nil {
+			return uncheckedSchema(path, "%v", err)
+		}
+		if err = v.validate(refSchema, refRoot, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+	if schemaType, found := schema["type"]; found && !matchesAnyType(schemaType, value) {
+		return fmt.Errorf("%s must be of type %v", path, schemaType)
+	}
+	if enum, found := schema["enum"].([]interface{}); found {
+		matched := false
+		for _, allowed := range enum {
+			matched = matched || reflect.DeepEqual(allowed, value)
+		}
+		if !matched {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+	if constant, found := schema["const"]; found && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s must be %v", path, constant)
+	}
+	if err := v.validateCombinations(schema, root, value, path, depth); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return v.validateObject(schema, root, value, path, depth)
+	case []interface{}:
+		return v.validateArray(schema, root, value, path, depth)
+	case string:
+		return validateString(schema, value, path)
+	case float64:
+		return validateNumber(schema, value, path)
+	}
+	return nil
+}
+
+// resolve returns the schema of the $ref with the root of its local $refs
+func (v *schemaValidator) resolve(ref string, root map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	location, fragment, _ := strings.Cut(ref, "#")
+	if location != "" {
+		loaded, found := v.loaded[location]
+		if !found {
+			if v.loader == nil {
+				return nil, nil, fmt.Errorf("the schema %s of the $ref can't be loaded", location)
+			}
+			var err error
+			if loaded, err = v.loader(location); err != nil {
+				return nil, nil, fmt.Errorf("failed to load the schema %s of the $ref: %w", location, err)
+			}
+			v.loaded[location] = loaded
+		}
+		root = loaded
+	}
+	schema := root
+	for _, token := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		subSchema, ok := schema[token].(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("the $ref %s isn't found in the schema", ref)
+		}
+		schema = subSchema
+	}
+	return schema, root, nil
+}
+
+// validateCombinations validates the allOf, anyOf, oneOf and not of the schema
+func (v *schemaValidator) validateCombinations(schema map[string]interface{}, root map[string]interface{}, value interface{}, path string, depth int) error {
+	if allOf, found := schema["allOf"]; found {
+		subSchemas, err := schemaList(allOf, "allOf", path)
+		if err != nil {
+			return err
+		}
+		for _, subSchema := range subSchemas {
+			if err = v.validate(subSchema, root, value, path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		combination, found := schema[keyword]
+		if !found {
+			continue
+		}
+		subSchemas, err := schemaList(combination, keyword, path)
+		if err != nil {
+			return err
+		}
+		matched := 0
+		for _, subSchema := range subSchemas {
+			err = v.validate(subSchema, root, value, path, depth+1)
+			if errors.Is(err, ErrUncheckedSchema) {
+				return err
+			}
+			if err == nil {
+				matched++
+			}
+		}
+		if matched == 0 || (keyword == "oneOf" && matched > 1) {
+			return fmt.Errorf("%s must match %s of the %d schemas of %s, it matches %d", path, map[string]string{"anyOf": "any", "oneOf": "one"}[keyword], len(subSchemas), keyword, matched)
+		}
+	}
+	if not, found := schema["not"]; found {
+		notSchema, ok := not.(map[string]interface{})
+		if !ok {
+			return uncheckedSchema(path, "not must be a schema")
+		}
+		err := v.validate(notSchema, root, value, path, depth+1)
+		if errors.Is(err, ErrUncheckedSchema) {
+			return err
+		}
+		if err == nil {
+			return fmt.Errorf("%s must not match the schema of not", path)
+		}
+	}
+	return nil
+}
+
+// validateObject validates the required and the defined properties of the object
+func (v *schemaValidator) validateObject(schema map[string]interface{}, root map[string]interface{}, object map[string]interface{}, path string, depth int) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, found := object[fmt.Sprint(name)]; !found {
+				return fmt.Errorf("%s.%v is required", path, name)
+			}
+		}
+	}
+	if minProperties, ok := schema["minProperties"].(float64); ok && float64(len(object)) < minProperties {
+		return fmt.Errorf("%s must have at least %v properties", path, minProperties)
+	}
+	if maxProperties, ok := schema["maxProperties"].(float64); ok && float64(len(object)) > maxProperties {
+		return fmt.Errorf("%s must have at most %v properties", path, maxProperties)
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPath := path + "." + name
+		defined := false
+		if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+			defined = true
+			if err := v.validate(propertySchema, root, object[name], propertyPath, depth+1); err != nil {
+				return err
+			}
+		}
+		for pattern, patternSchema := range patternProperties {
+			matched, err := regexp.MatchString(pattern, name)
+			if err != nil {
+				return uncheckedSchema(path, "invalid pattern property: %v", err)
+			}
+			if patternSchema, ok := patternSchema.(map[string]interface{}); ok && matched {
+				defined = true
+				if err = v.validate(patternSchema, root, object[name], propertyPath, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		if defined {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s is not allowed", propertyPath)
+			}
+		case map[string]interface{}:
+			if err := v.validate(additional, root, object[name], propertyPath, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateArray validates the items of the array, the items are a schema of every item or a schema per position
+func (v *schemaValidator) validateArray(schema map[string]interface{}, root map[string]interface{}, array []interface{}, path string, depth int) error {
+	if minItems, ok := schema["minItems"].(float64); ok && float64(len(array)) < minItems {
+		return fmt.Errorf("%s must have at least %v items", path, minItems)
+	}
+	if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(array)) > maxItems {
+		return fmt.Errorf("%s must have at most %v items", path, maxItems)
+	}
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if reflect.DeepEqual(array[i], array[j]) {
+					return fmt.Errorf("%s must have unique items, %d and %d are equal", path, i, j)
+				}
+			}
+		}
+	}
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		for i, item := range array {
+			if err := v.validate(items, root, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		itemSchemas, err := schemaList(items, "items", path)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(array) && i < len(itemSchemas); i++ {
+			if err = v.validate(itemSchemas[i], root, array[i], fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if contains, ok := schema["contains"].(map[string]interface{}); ok {
+		for _, item := range array {
+			err := v.validate(contains, root, item, path, depth+1)
+			if errors.Is(err, ErrUncheckedSchema) {
+				return err
+			}
+			if err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must contain an item that matches the schema of contains", path)
+	}
+	return nil
+}
+
+// validateString validates the length, the pattern and the format of the string
+func validateString(schema map[string]interface{}, value string, path string) error {
+	if minLength, ok := schema["minLength"].(float64); ok && float64(len([]rune(value))) < minLength {
+		return fmt.Errorf("%s must have at least %v characters", path, minLength)
+	}
+	if maxLength, ok := schema["maxLength"].(float64); ok && float64(len([]rune(value))) > maxLength {
+		return fmt.Errorf("%s must have at most %v characters", path, maxLength)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		matched, err := regexp.MatchString(pattern, value)
+		if err != nil {
+			return uncheckedSchema(path, "invalid pattern: %v", err)
+		}
+		if !matched {
+			return fmt.Errorf("%s must match the pattern %s", path, pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok {
+		return validateFormat(format, value, path)
+	}
+	return nil
+}
+
+// validateFormat validates the formats date-time, date, uri and email, another format isn't supported
+func validateFormat(format string, value string, path string) error {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "uri":
+		var uri *url.URL
+		if uri, err = url.Parse(value); err == nil && uri.Scheme == "" {
+			err = fmt.Errorf("the uri %s has no scheme", value)
+		}
+	case "email":
+		_, err = mail.ParseAddress(value)
+	default:
+		return uncheckedSchema(path, "the format %s isn't supported", format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s must be a %s: %v", path, format, err)
+	}
+	return nil
+}
+
+// validateNumber validates the range of the number
+func validateNumber(schema map[string]interface{}, value float64, path string) error {
+	if minimum, ok := schema["minimum"].(float64); ok && value < minimum {
+		return fmt.Errorf("%s must be at least %v", path, minimum)
+	}
+	if maximum, ok := schema["maximum"].(float64); ok && value > maximum {
+		return fmt.Errorf("%s must be at most %v", path, maximum)
+	}
+	if minimum, ok := schema["exclusiveMinimum"].(float64); ok && value <= minimum {
+		return fmt.Errorf("%s must be more than %v", path, minimum)
+	}
+	if maximum, ok := schema["exclusiveMaximum"].(float64); ok && value >= maximum {
+		return fmt.Errorf("%s must be less than %v", path, maximum)
+	}
+	if multipleOf, ok := schema["multipleOf"].(float64); ok && multipleOf > 0 {
+		if quotient := value / multipleOf; quotient != math.Trunc(quotient) {
+			return fmt.Errorf("%s must be a multiple of %v", path, multipleOf)
+		}
+	}
+	return nil
+}
+
+// schemaList returns the schemas of the keyword, the keyword must be an array of schemas
+func schemaList(list interface{}, keyword string, path string) ([]map[string]interface{}, error) {
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil, uncheckedSchema(path, "%s must be an array of schemas", keyword)
+	}
+	schemas := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		schema, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, uncheckedSchema(path, "%s must be an array of schemas", keyword)
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+// uncheckedSchema returns ErrUncheckedSchema with the path and the reason
+func uncheckedSchema(path string, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %w: %s", path, ErrUncheckedSchema, fmt.Sprintf(format, args...))
+}
+
+// matchesAnyType returns true when the json value is of the type, or one of the types, of the schema
+func matchesAnyType(schemaType interface{}, value interface{}) bool {
+	types, ok := schemaType.([]interface{})
+	if !ok {
+		types = []interface{}{schemaType}
+	}
+	for _, jsonType := range types {
+		if matchesType(fmt.Sprint(jsonType), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(jsonType string, value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return jsonType == "null"
+	case bool:
+		return jsonType == "boolean"
+	case string:
+		return jsonType == "string"
+	case float64:
+		return jsonType == "number" || (jsonType == "integer" && value == math.Trunc(value))
+	case []interface{}:
+		return jsonType == "array"
+	case map[string]interface{}:
+		return jsonType == "object"
+	}
+	return false
+}
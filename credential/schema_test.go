@@ -0,0 +1,135 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gossif/admin/credential"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id", "legalName"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "pattern": "^did:"},
+			"legalName": {"type": "string", "maxLength": 10},
+			"employees": {"type": "integer"},
+			"country": {"enum": ["BE", "NL"]},
+			"activities": {"type": "array", "items": {"type": "string"}}
+		}
+	}`), &schema))
+
+	testCases := map[string]bool{
+		`{"id": "did:web:example.org", "legalName": "Acme"}`:                                       true,
+		`{"id": "did:web:example.org", "legalName": "Acme", "employees": 12, "country": "BE"}`:     true,
+		`{"id": "did:web:example.org", "legalName": "Acme", "activities": ["retail", "services"]}`: true,
+		`{"id": "did:web:example.org"}`:                                                            false,
+		`{"id": "urn:uuid:1234", "legalName": "Acme"}`:                                             false,
+		`{"id": "did:web:example.org", "legalName": "Acme Corporation"}`:                           false,
+		`{"id": "did:web:example.org", "legalName": "Acme", "employees": 1.5}`:                     false,
+		`{"id": "did:web:example.org", "legalName": "Acme", "country": "FR"}`:                      false,
+		`{"id": "did:web:example.org", "legalName": "Acme", "activities": [1]}`:                    false,
+		`{"id": "did:web:example.org", "legalName": "Acme", "vatNumber": "BE0123"}`:                false,
+		`["did:web:example.org"]`:                                                                  false,
+	}
+	for value, valid := range testCases {
+		var decoded interface{}
+		require.NoError(t, json.Unmarshal([]byte(value), &decoded))
+		err := credential.ValidateSchema(schema, decoded, nil)
+		assert.Equal(t, valid, err == nil, value)
+	}
+}
+
+func TestValidateSchemaKeywords(t *testing.T) {
+	decode := func(document string) map[string]interface{} {
+		decoded := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(document), &decoded))
+		return decoded
+	}
+	// a schema of the trusted schemas registry that extends the attestation schema
+	attestation := decode(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["id", "issuanceDate"],
+		"properties": {
+			"id": {"type": "string", "format": "uri"},
+			"issuanceDate": {"type": "string", "format": "date-time"}
+		}
+	}`)
+	schema := decode(`{
+		"title": "Legal entity",
+		"allOf": [
+			{"$ref": "https://schemas.example.org/attestation.json"},
+			{"properties": {"credentialSubject": {"$ref": "#/$defs/subject"}}}
+		],
+		"$defs": {
+			"subject": {
+				"type": "object",
+				"required": ["legalName"],
+				"properties": {
+					"legalName": {"type": "string"},
+					"employees": {"type": "integer", "minimum": 1, "exclusiveMaximum": 1000},
+					"activities": {"type": "array", "minItems": 1, "uniqueItems": true},
+					"registration": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+					"email": {"anyOf": [{"type": "string", "format": "email"}, {"type": "null"}]}
+				}
+			}
+		}
+	}`)
+	loads := 0
+	loader := func(id string) (map[string]interface{}, error) {
+		loads++
+		if id != "https://schemas.example.org/attestation.json" {
+			return nil, errors.New("not found")
+		}
+		return attestation, nil
+	}
+
+	testCases := map[string]bool{
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme"}}`:                                        true,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "employees": 12, "registration": 1234}}`: true,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "email": "info@example.org"}}`:           true,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01", "credentialSubject": {"legalName": "Acme"}}`:                                                  false,
+		`{"id": "uuid", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme"}}`:                                              false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {}}`:                                                           false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "employees": 0}}`:                        false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "employees": 1000}}`:                     false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "activities": []}}`:                      false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "activities": ["retail", "retail"]}}`:    false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "registration": true}}`:                  false,
+		`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme", "email": "not an email"}}`:               false,
+	}
+	for value, valid := range testCases {
+		err := credential.ValidateSchema(schema, decode(value), loader)
+		assert.Equal(t, valid, err == nil, value, err)
+		assert.NotErrorIs(t, err, credential.ErrUncheckedSchema, value)
+	}
+	assert.Equal(t, len(testCases), loads, "the $ref is loaded once per validation")
+
+	t.Run("Unchecked", func(t *testing.T) {
+		credentialValue := decode(`{"id": "urn:uuid:1", "issuanceDate": "2023-01-01T00:00:00Z", "credentialSubject": {"legalName": "Acme"}}`)
+		err := credential.ValidateSchema(schema, credentialValue, nil)
+		assert.ErrorIs(t, err, credential.ErrUncheckedSchema, "a $ref that can't be loaded")
+
+		for _, unchecked := range []string{
+			`{"if": {"type": "object"}, "then": {"required": ["id"]}}`,
+			`{"properties": {"id": {"type": "string", "format": "hostname"}}}`,
+			`{"not": {"dependentRequired": {"id": ["type"]}}}`,
+			`{"anyOf": [{"type": "object"}, {"unevaluatedProperties": false}]}`,
+			`{"$ref": "#/$defs/missing"}`,
+			`{"$ref": "#", "type": "object"}`,
+		} {
+			err := credential.ValidateSchema(decode(unchecked), credentialValue, loader)
+			assert.ErrorIs(t, err, credential.ErrUncheckedSchema, unchecked)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Formats of a credential
+const (
+	FormatJwt    string = "jwt"
+	FormatJsonLd string = "json-ld"
+)
+
+// Summary describes a credential without verifying it
+type Summary struct {
+	Format         string   `json:"format"`
+	Id             string   `json:"id"`
+	Issuer         string   `json:"issuer"`
+	Subject        string   `json:"subject,omitempty"`
+	Types          []string `json:"type"`
+	IssuanceDate   string   `json:"issuanceDate,omitempty"`
+	ExpirationDate string   `json:"expirationDate,omitempty"`
+}
+
+// Summarize describes the credential, a JWT with a vc claim or a JSON-LD credential. The signature or proof isn't
+// verified.
+func Summarize(data []byte) (Summary, error) {
+	text := strings.TrimSpace(string(data))
+	verdict := Verdict{}
+	summary := Summary{Format: FormatJwt}
+	if strings.HasPrefix(text, "{") {
+		summary.Format = FormatJsonLd
+		if err := json.Unmarshal([]byte(text), &verdict.Credential); err != nil {
+			return Summary{}, fmt.Errorf("%w: %s", ErrInvalidCredential, err)
+		}
+		verdict.describeCredential()
+		if verdict.Issuer == "" || !containsType(verdict.Types, TypeVerifiableCredential) {
+			return Summary{}, fmt.Errorf("%w: the json isn't a verifiable credential", ErrInvalidCredential)
+		}
+	} else if _, _, err := verdict.parse(text); err != nil {
+		return Summary{}, fmt.Errorf("%w: %s", ErrInvalidCredential, err)
+	}
+	summary.Id = verdict.Id
+	summary.Issuer = verdict.Issuer
+	summary.Subject = verdict.Subject
+	summary.IssuanceDate = verdict.IssuanceDate
+	summary.ExpirationDate = verdict.ExpirationDate
+	for _, credentialType := range verdict.Types {
+		summary.Types = append(summary.Types, fmt.Sprint(credentialType))
+	}
+	return summary, nil
+}
@@ -0,0 +1,459 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// Names of the checks of the verification
+const (
+	CheckFormat             string = "format"
+	CheckIssuer             string = "issuer"
+	CheckVerificationMethod string = "verificationMethod"
+	CheckSignature          string = "signature"
+	CheckValidity           string = "validity"
+	CheckSchema             string = "credentialSchema"
+)
+
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// Resolver returns the did document of the did
+type Resolver func(did string) (map[string]interface{}, error)
+
+// SchemaLoader returns the json schema with the id
+type SchemaLoader func(id string) (map[string]interface{}, error)
+
+// RetiredKey is a verification method that is removed from the did document, it verifies the jwts issued within
+// its validity
+type RetiredKey struct {
+	Key        jwk.Key
+	Purposes   []string
+	ValidFrom  time.Time
+	ValidUntil time.Time
+}
+
+// ValidAt returns true when the key was valid at the time, the start of the validity is truncated to the second
+// precision of the jwt claims
+func (k RetiredKey) ValidAt(t time.Time) bool {
+	return !t.Before(k.ValidFrom.Truncate(time.Second)) && !t.After(k.ValidUntil)
+}
+
+// KeyHistory returns the retired verification method of the kid
+type KeyHistory func(kid string) (RetiredKey, bool)
+
+// verifyOptions are the options of the verification
+type verifyOptions struct {
+	now          time.Time
+	skew         time.Duration
+	schemaLoader SchemaLoader
+	keyHistory   KeyHistory
+	audience     string
+	nonce        string
+}
+
+type VerifyOption func(*verifyOptions)
+
+// newVerifyOptions returns the options of the verification with the defaults
+func newVerifyOptions(opts []VerifyOption) verifyOptions {
+	o := verifyOptions{now: time.Now(), skew: time.Minute}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTime sets the time of the validity check, defaults to now
+func WithTime(now time.Time) VerifyOption {
+	return func(o *verifyOptions) {
+		o.now = now
+	}
+}
+
+// WithSchemaLoader sets the loader of the credential schemas, the schema isn't checked without a loader
+func WithSchemaLoader(schemaLoader SchemaLoader) VerifyOption {
+	return func(o *verifyOptions) {
+		o.schemaLoader = schemaLoader
+	}
+}
+
+// WithKeyHistory sets the lookup of the retired verification methods, a kid that isn't in the did document is
+// looked up in the key history. The retired methods aren't looked up by default.
+func WithKeyHistory(keyHistory KeyHistory) VerifyOption {
+	return func(o *verifyOptions) {
+		o.keyHistory = keyHistory
+	}
+}
+
+// WithAudience sets the audience the presentation must be addressed to, the audience isn't checked by default
+func WithAudience(audience string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.audience = audience
+	}
+}
+
+// WithNonce sets the nonce the presentation must contain, the nonce isn't checked by default
+func WithNonce(nonce string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.nonce = nonce
+	}
+}
+
+// Check is the outcome of a step of the verification
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Verdict is the outcome of the verification of a credential, the credential is valid when all the checks passed
+type Verdict struct {
+	Valid          bool                   `json:"valid"`
+	Id             string                 `json:"id,omitempty"`
+	Issuer         string                 `json:"issuer,omitempty"`
+	Subject        string                 `json:"subject,omitempty"`
+	Types          []interface{}          `json:"type,omitempty"`
+	KeyId          string                 `json:"kid,omitempty"`
+	IssuanceDate   string                 `json:"issuanceDate,omitempty"`
+	ExpirationDate string                 `json:"expirationDate,omitempty"`
+	Checks         []Check                `json:"checks"`
+	Credential     map[string]interface{} `json:"-"`
+}
+
+// Err returns ErrInvalidCredential with the failed checks, nil for a valid credential
+func (v Verdict) Err() error {
+	if v.Valid {
+		return nil
+	}
+	failed := []string{}
+	for _, check := range v.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name+": "+check.Message)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidCredential, strings.Join(failed, ", "))
+}
+
+func (v *Verdict) check(name string, err error) bool {
+	if err != nil {
+		v.Checks = append(v.Checks, Check{Name: name, Message: err.Error()})
+		v.Valid = false
+		return false
+	}
+	v.Checks = append(v.Checks, Check{Name: name, Passed: true})
+	return true
+}
+
+// VerifyJwt verifies the credential of the JWT. The issuer did is resolved to select the verification method of
+// the kid, the method must be an assertion method of the issuer. The verification stops at the first failed check
+// of the format, issuer, verification method and signature.
+// See https://www.w3.org/TR/vc-data-model/#jwt-decoding
+func VerifyJwt(token string, resolve Resolver, opts ...VerifyOption) Verdict {
+	o := newVerifyOptions(opts)
+	verdict := Verdict{Valid: true}
+	token = strings.TrimSpace(token)
+
+	// format
+	headers, claims, err := verdict.parse(token)
+	if !verdict.check(CheckFormat, err) {
+		return verdict
+	}
+
+	// issuer
+	document, err := resolve(verdict.Issuer)
+	if !verdict.check(CheckIssuer, err) {
+		return verdict
+	}
+
+	// verification method
+	publicKey, err := methodKey(document, verdict.Issuer, verdict.KeyId, "assertionMethod")
+	if err != nil {
+		if retiredKey, found, retiredErr := retiredMethodKey(o.keyHistory, verdict.Issuer, verdict.KeyId, "assertionMethod", claims); found {
+			publicKey, err = retiredKey, retiredErr
+		}
+	}
+	if !verdict.check(CheckVerificationMethod, err) {
+		return verdict
+	}
+
+	// signature
+	if !verdict.check(CheckSignature, verifySignature(token, headers.Algorithm(), publicKey)) {
+		return verdict
+	}
+
+	verdict.check(CheckValidity, validity(claims, verdict, o))
+	verdict.check(CheckSchema, validateCredentialSchemas(verdict.Credential, o.schemaLoader))
+	return verdict
+}
+
+// parse parses the jwt and describes the credential of the vc claim, the signature isn't verified
+func (v *Verdict) parse(token string) (jws.Headers, jwt.Token, error) {
+	message, err := jws.Parse([]byte(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(message.Signatures()) != 1 {
+		return nil, nil, errors.New("the jwt must have a single signature")
+	}
+	headers := message.Signatures()[0].ProtectedHeaders()
+	v.KeyId = headers.KeyID()
+	claims, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if v.Credential, err = jsonClaim(claims, "vc"); err != nil {
+		return nil, nil, err
+	}
+	v.describe(claims)
+	return headers, claims, matchRegisteredClaims(*v, claims)
+}
+
+// jsonClaim returns the object of the claim, normalized as decoded json
+func jsonClaim(claims jwt.Token, name string) (map[string]interface{}, error) {
+	value, found := claims.Get(name)
+	if !found {
+		return nil, fmt.Errorf("the jwt has no %s claim", name)
+	}
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	if err = json.Unmarshal(valueBytes, &object); err != nil {
+		return nil, fmt.Errorf("the %s claim is not an object: %w", name, err)
+	}
+	return object, nil
+}
+
+// describe sets the properties of the credential, the registered claims take precedence. The nbf and exp claims
+// are the issuance and expiration date of a credential without them.
+func (v *Verdict) describe(claims jwt.Token) {
+	v.describeCredential()
+	if claims.Issuer() != "" {
+		v.Issuer = claims.Issuer()
+	}
+	if claims.Subject() != "" {
+		v.Subject = claims.Subject()
+	}
+	if claims.JwtID() != "" {
+		v.Id = claims.JwtID()
+	}
+	if v.IssuanceDate == "" && !claims.NotBefore().IsZero() {
+		v.IssuanceDate = claims.NotBefore().UTC().Format(time.RFC3339)
+	}
+	if v.ExpirationDate == "" && !claims.Expiration().IsZero() {
+		v.ExpirationDate = claims.Expiration().UTC().Format(time.RFC3339)
+	}
+}
+
+// describeCredential sets the properties of the credential
+func (v *Verdict) describeCredential() {
+	v.Id, _ = v.Credential["id"].(string)
+	v.Issuer = credentialIssuer(v.Credential)
+	v.Types, _ = v.Credential["type"].([]interface{})
+	v.IssuanceDate, _ = v.Credential["issuanceDate"].(string)
+	v.ExpirationDate, _ = v.Credential["expirationDate"].(string)
+	if credentialSubject, ok := v.Credential["credentialSubject"].(map[string]interface{}); ok {
+		v.Subject, _ = credentialSubject["id"].(string)
+	}
+}
+
+// matchRegisteredClaims checks that the registered claims match the credential
+func matchRegisteredClaims(verdict Verdict, claims jwt.Token) error {
+	if verdict.Issuer == "" {
+		return errors.New("the credential has no issuer")
+	}
+	if issuer := credentialIssuer(verdict.Credential); issuer != "" && issuer != verdict.Issuer {
+		return fmt.Errorf("the iss claim %s doesn't match the issuer %s", verdict.Issuer, issuer)
+	}
+	if credentialSubject, ok := verdict.Credential["credentialSubject"].(map[string]interface{}); ok {
+		if subject, ok := credentialSubject["id"].(string); ok && claims.Subject() != "" && subject != claims.Subject() {
+			return fmt.Errorf("the sub claim %s doesn't match the credential subject %s", claims.Subject(), subject)
+		}
+	}
+	if !containsType(verdict.Types, TypeVerifiableCredential) {
+		return fmt.Errorf("the credential must be of type %s", TypeVerifiableCredential)
+	}
+	return nil
+}
+
+// credentialIssuer returns the issuer of the credential, the issuer is an uri or an object with an id
+func credentialIssuer(credential map[string]interface{}) string {
+	switch issuer := credential["issuer"].(type) {
+	case string:
+		return issuer
+	case map[string]interface{}:
+		id, _ := issuer["id"].(string)
+		return id
+	}
+	return ""
+}
+
+func containsType(types []interface{}, credentialType string) bool {
+	for _, t := range types {
+		if t == credentialType {
+			return true
+		}
+	}
+	return false
+}
+
+// methodKey returns the public key of the verification method of the kid, the method must have the verification
+// relationship (f.e. assertionMethod) with the did
+func methodKey(document map[string]interface{}, controller string, kid string, relationship string) (jwk.Key, error) {
+	if kid == "" {
+		return nil, errors.New("the jwt has no kid header")
+	}
+	if strings.HasPrefix(kid, "#") {
+		kid = controller + kid
+	}
+	if !strings.HasPrefix(kid, controller+"#") {
+		return nil, fmt.Errorf("the kid %s isn't a verification method of %s", kid, controller)
+	}
+	publicKey, err := did.VerificationMethodKey(document, kid)
+	if err != nil {
+		return nil, err
+	}
+	for _, methodRelationship := range did.VerificationRelationships(document, kid) {
+		if methodRelationship == relationship {
+			return publicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("the verification method %s isn't in the %s of %s", kid, relationship, controller)
+}
+
+// retiredMethodKey returns the public key of the retired verification method of the kid in the key history, found
+// is false when the kid isn't retired. The method must have had the verification relationship with the did, and
+// the jwt must be issued within the validity of the method.
+func retiredMethodKey(keyHistory KeyHistory, controller string, kid string, relationship string, claims jwt.Token) (jwk.Key, bool, error) {
+	if keyHistory == nil || kid == "" {
+		return nil, false, nil
+	}
+	if strings.HasPrefix(kid, "#") {
+		kid = controller + kid
+	}
+	if !strings.HasPrefix(kid, controller+"#") {
+		return nil, false, nil
+	}
+	retiredKey, found := keyHistory(kid)
+	if !found {
+		return nil, false, nil
+	}
+	issuedAt := claims.IssuedAt()
+	if issuedAt.IsZero() {
+		issuedAt = claims.NotBefore()
+	}
+	if issuedAt.IsZero() {
+		return nil, true, fmt.Errorf("the verification method %s is retired, the jwt has no iat to check its validity", kid)
+	}
+	if !retiredKey.ValidAt(issuedAt) {
+		return nil, true, fmt.Errorf("the jwt is issued at %s, outside the validity of the retired verification method %s",
+			issuedAt.UTC().Format(time.RFC3339), kid)
+	}
+	for _, purpose := range retiredKey.Purposes {
+		if purpose == relationship {
+			return retiredKey.Key, true, nil
+		}
+	}
+	return nil, true, fmt.Errorf("the retired verification method %s wasn't in the %s of %s", kid, relationship, controller)
+}
+
+// verifySignature verifies the signature of the jws with the key, the algorithm of the header must match the key
+func verifySignature(token string, alg jwa.SignatureAlgorithm, publicKey jwk.Key) error {
+	keyAlg, err := SignatureAlgorithm(publicKey)
+	if err != nil {
+		return err
+	}
+	if keyAlg != alg {
+		return fmt.Errorf("the algorithm %s doesn't match the key", alg)
+	}
+	_, err = jws.Verify([]byte(token), jws.WithKey(alg, publicKey))
+	return err
+}
+
+// validity checks the registered time claims and the validity period of the credential
+func validity(claims jwt.Token, verdict Verdict, o verifyOptions) error {
+	if err := validateTimeClaims(claims, o); err != nil {
+		return err
+	}
+	if verdict.IssuanceDate != "" {
+		issuanceDate, err := time.Parse(time.RFC3339, verdict.IssuanceDate)
+		if err != nil {
+			return fmt.Errorf("invalid issuanceDate: %w", err)
+		}
+		if o.now.Add(o.skew).Before(issuanceDate) {
+			return fmt.Errorf("the credential is valid from %s", verdict.IssuanceDate)
+		}
+	}
+	if verdict.ExpirationDate != "" {
+		expirationDate, err := time.Parse(time.RFC3339, verdict.ExpirationDate)
+		if err != nil {
+			return fmt.Errorf("invalid expirationDate: %w", err)
+		}
+		if o.now.Add(-o.skew).After(expirationDate) {
+			return fmt.Errorf("the credential expired at %s", verdict.ExpirationDate)
+		}
+	}
+	return nil
+}
+
+// validateTimeClaims checks the iat, nbf and exp claims at the time of the verification
+func validateTimeClaims(claims jwt.Token, o verifyOptions) error {
+	return jwt.Validate(claims, jwt.WithClock(jwt.ClockFunc(func() time.Time { return o.now })), jwt.WithAcceptableSkew(o.skew))
+}
+
+// validateCredentialSchemas validates the credential subject against the schemas of the credential
+func validateCredentialSchemas(credential map[string]interface{}, schemaLoader SchemaLoader) error {
+	schemas := []interface{}{}
+	switch credentialSchema := credential["credentialSchema"].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		schemas = credentialSchema
+	default:
+		schemas = append(schemas, credentialSchema)
+	}
+	if schemaLoader == nil {
+		return errors.New("the credential schema can't be loaded")
+	}
+	for _, credentialSchema := range schemas {
+		schemaObject, ok := credentialSchema.(map[string]interface{})
+		if !ok {
+			return errors.New("the credential schema must be an object with an id and a type")
+		}
+		schemaId, _ := schemaObject["id"].(string)
+		if schemaId == "" {
+			return errors.New("the credential schema has no id")
+		}
+		schema, err := schemaLoader(schemaId)
+		if err != nil {
+			return fmt.Errorf("failed to load the schema %s: %w", schemaId, err)
+		}
+		if err = validateSubjectSchema(schema, credential, schemaLoader); err != nil {
+			return fmt.Errorf("%s: %w", schemaId, err)
+		}
+	}
+	return nil
+}
+
+// validateSubjectSchema validates the credential against a schema of the whole credential (with a credentialSubject
+// property), or else the credential subject against the schema. The $refs of the schema are loaded with the loader.
+func validateSubjectSchema(schema map[string]interface{}, credential map[string]interface{}, schemaLoader SchemaLoader) error {
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		if _, ok := properties["credentialSubject"]; ok {
+			return ValidateSchema(schema, credential, schemaLoader)
+		}
+	}
+	return ValidateSchema(schema, credential["credentialSubject"], schemaLoader)
+}
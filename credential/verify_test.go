@@ -0,0 +1,195 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package credential_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/credential"
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failedCheck returns the name of the first failed check
+func failedCheck(verdict credential.Verdict) string {
+	for _, check := range verdict.Checks {
+		if !check.Passed {
+			return check.Name
+		}
+	}
+	return ""
+}
+
+func TestVerifyJwt(t *testing.T) {
+	key := generateKey(t)
+	publicKey, err := key.PublicKey()
+	require.NoError(t, err)
+	document := did.NewDocument(testIssuer, publicKey)
+	resolve := func(identifier string) (map[string]interface{}, error) {
+		if identifier != testIssuer {
+			return nil, errors.New("not found")
+		}
+		return document, nil
+	}
+	schemaLoader := func(id string) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"id", "legalName"},
+			"properties": map[string]interface{}{
+				"legalName": map[string]interface{}{"type": "string", "minLength": float64(1)},
+			},
+		}, nil
+	}
+	signJwt := func(vc map[string]interface{}, key jwk.Key) string {
+		signed, err := credential.SignJwt(vc, key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, []string{"VerifiableAttestation"}, map[string]interface{}{"legalName": "Acme"},
+			credential.WithSchema("https://example.org/schema.json"), credential.WithExpirationDate(time.Now().Add(time.Hour)))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.True(t, verdict.Valid, verdict.Checks)
+		assert.NoError(t, verdict.Err())
+		assert.Equal(t, testIssuer, verdict.Issuer)
+		assert.Equal(t, testSubject, verdict.Subject)
+		assert.Equal(t, key.KeyID(), verdict.KeyId)
+		assert.Len(t, verdict.Checks, 6)
+	})
+	t.Run("Format", func(t *testing.T) {
+		verdict := credential.VerifyJwt("not a jwt", resolve)
+		assert.False(t, verdict.Valid)
+		assert.Equal(t, credential.CheckFormat, failedCheck(verdict))
+		assert.ErrorIs(t, verdict.Err(), credential.ErrInvalidCredential)
+	})
+	t.Run("UnknownIssuer", func(t *testing.T) {
+		otherKey := generateKey(t)
+		otherKey.Set(jwk.KeyIDKey, "did:web:other.org#key-1")
+		verdict := credential.VerifyJwt(signJwt(credential.New("did:web:other.org", testSubject, nil, nil), otherKey), resolve)
+		assert.Equal(t, credential.CheckIssuer, failedCheck(verdict))
+	})
+	t.Run("UnknownKey", func(t *testing.T) {
+		otherKey := generateKey(t)
+		otherKey.Set(jwk.KeyIDKey, testIssuer+"#key-2")
+		verdict := credential.VerifyJwt(signJwt(credential.New(testIssuer, testSubject, nil, nil), otherKey), resolve)
+		assert.Equal(t, credential.CheckVerificationMethod, failedCheck(verdict))
+	})
+	t.Run("Signature", func(t *testing.T) {
+		otherKey := generateKey(t)
+		verdict := credential.VerifyJwt(signJwt(credential.New(testIssuer, testSubject, nil, nil), otherKey), resolve)
+		assert.Equal(t, credential.CheckSignature, failedCheck(verdict))
+	})
+	t.Run("Expired", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, nil,
+			credential.WithIssuanceDate(time.Now().Add(-2*time.Hour)), credential.WithExpirationDate(time.Now().Add(-time.Hour)))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve)
+		assert.Equal(t, credential.CheckValidity, failedCheck(verdict))
+	})
+	t.Run("NotYetValid", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, nil, credential.WithIssuanceDate(time.Now().Add(time.Hour)))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve)
+		assert.Equal(t, credential.CheckValidity, failedCheck(verdict))
+
+		verdict = credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithTime(time.Now().Add(2*time.Hour)))
+		assert.True(t, verdict.Valid, verdict.Checks)
+	})
+	t.Run("Schema", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, map[string]interface{}{"legalName": ""}, credential.WithSchema("https://example.org/schema.json"))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+	})
+	t.Run("SchemaString", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, map[string]interface{}{"legalName": "Acme"})
+		vc["credentialSchema"] = "https://example.org/schema.json"
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+
+		vc["credentialSchema"] = []interface{}{"https://example.org/schema.json"}
+		verdict = credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+	})
+	t.Run("SchemaArray", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, map[string]interface{}{"legalName": "Acme"})
+		vc["credentialSchema"] = []interface{}{
+			map[string]interface{}{"id": "https://example.org/schema.json", "type": credential.TypeJsonSchema},
+			map[string]interface{}{"id": "https://example.org/other.json", "type": credential.TypeJsonSchema},
+		}
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.True(t, verdict.Valid, verdict.Checks)
+
+		vc["credentialSubject"].(map[string]interface{})["legalName"] = ""
+		verdict = credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(schemaLoader))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+	})
+	t.Run("SchemaRef", func(t *testing.T) {
+		credentialSchemaLoader := func(id string) (map[string]interface{}, error) {
+			if id == "https://example.org/schema.json" {
+				return schemaLoader(id)
+			}
+			// the schema of the whole credential refers to the schema of the subject
+			return map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"issuer", "credentialSubject"},
+				"allOf": []interface{}{
+					map[string]interface{}{"properties": map[string]interface{}{
+						"credentialSubject": map[string]interface{}{"$ref": "https://example.org/schema.json"},
+					}},
+				},
+				"properties": map[string]interface{}{"credentialSubject": map[string]interface{}{"type": "object"}},
+			}, nil
+		}
+		vc := credential.New(testIssuer, testSubject, nil, map[string]interface{}{"legalName": "Acme"}, credential.WithSchema("https://example.org/credential.json"))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(credentialSchemaLoader))
+		assert.True(t, verdict.Valid, verdict.Checks)
+
+		vc["credentialSubject"].(map[string]interface{})["legalName"] = ""
+		verdict = credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(credentialSchemaLoader))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+	})
+	t.Run("SchemaUnchecked", func(t *testing.T) {
+		vc := credential.New(testIssuer, testSubject, nil, map[string]interface{}{"legalName": "Acme"}, credential.WithSchema("https://example.org/schema.json"))
+		verdict := credential.VerifyJwt(signJwt(vc, key), resolve, credential.WithSchemaLoader(func(id string) (map[string]interface{}, error) {
+			if id == "https://example.org/base.json" {
+				// a keyword that isn't supported fails the check instead of passing unchecked
+				return map[string]interface{}{"if": map[string]interface{}{"type": "object"}}, nil
+			}
+			return map[string]interface{}{"allOf": []interface{}{map[string]interface{}{"$ref": "https://example.org/base.json"}}}, nil
+		}))
+		assert.Equal(t, credential.CheckSchema, failedCheck(verdict))
+		assert.Contains(t, verdict.Err().Error(), "the keyword if isn't supported")
+	})
+	t.Run("RetiredKey", func(t *testing.T) {
+		retiredKey := generateKey(t)
+		retiredKey.Set(jwk.KeyIDKey, testIssuer+"#retired")
+		retiredPublicKey, err := retiredKey.PublicKey()
+		require.NoError(t, err)
+		signed := signJwt(credential.New(testIssuer, testSubject, nil, nil), retiredKey)
+		keyHistory := func(validUntil time.Time, purposes ...string) credential.KeyHistory {
+			return func(kid string) (credential.RetiredKey, bool) {
+				if kid != retiredKey.KeyID() {
+					return credential.RetiredKey{}, false
+				}
+				return credential.RetiredKey{Key: retiredPublicKey, Purposes: purposes, ValidFrom: time.Now().Add(-time.Hour), ValidUntil: validUntil}, true
+			}
+		}
+
+		verdict := credential.VerifyJwt(signed, resolve)
+		assert.Equal(t, credential.CheckVerificationMethod, failedCheck(verdict))
+
+		verdict = credential.VerifyJwt(signed, resolve, credential.WithKeyHistory(keyHistory(time.Now().Add(time.Minute), "assertionMethod")))
+		assert.True(t, verdict.Valid, verdict.Checks)
+
+		// signed after the rotation
+		verdict = credential.VerifyJwt(signed, resolve, credential.WithKeyHistory(keyHistory(time.Now().Add(-time.Minute), "assertionMethod")))
+		assert.Equal(t, credential.CheckVerificationMethod, failedCheck(verdict))
+
+		verdict = credential.VerifyJwt(signed, resolve, credential.WithKeyHistory(keyHistory(time.Now().Add(time.Minute), "authentication")))
+		assert.Equal(t, credential.CheckVerificationMethod, failedCheck(verdict))
+	})
+}
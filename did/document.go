@@ -0,0 +1,155 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+var ErrVerificationMethodNotFound = errors.New("verification method not found")
+
+// verificationRelationships are the relationships of the verification methods of a did document
+var verificationRelationships = []string{"authentication", "assertionMethod", "keyAgreement", "capabilityInvocation", "capabilityDelegation"}
+
+// NewDocument returns a did document with the public keys as verification methods. The keys are
+// used for authentication and assertion.
+func NewDocument(did string, publicKeys ...jwk.Key) map[string]interface{} {
+	verificationMethods := []map[string]interface{}{}
+	keyIds := []string{}
+	for _, publicKey := range publicKeys {
+		verificationMethods = append(verificationMethods, map[string]interface{}{
+			"id":           publicKey.KeyID(),
+			"type":         "JsonWebKey2020",
+			"controller":   did,
+			"publicKeyJwk": publicKey,
+		})
+		keyIds = append(keyIds, publicKey.KeyID())
+	}
+	return map[string]interface{}{
+		"@context":           []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/suites/jws-2020/v1"},
+		"id":                 did,
+		"verificationMethod": verificationMethods,
+		"authentication":     keyIds,
+		"assertionMethod":    keyIds,
+	}
+}
+
+// AddVerificationMethod adds the public key as verification method with the id to the did document and adds
+// the id to the verification relationships (authentication, assertionMethod, capabilityInvocation)
+func AddVerificationMethod(document map[string]interface{}, methodId string, publicKey jwk.Key, relationships ...string) {
+	controller, _ := document["id"].(string)
+	document["verificationMethod"] = appendToList(document["verificationMethod"], map[string]interface{}{
+		"id":           methodId,
+		"type":         "JsonWebKey2020",
+		"controller":   controller,
+		"publicKeyJwk": publicKey,
+	})
+	for _, relationship := range relationships {
+		document[relationship] = appendToList(document[relationship], methodId)
+	}
+}
+
+// HasVerificationMethod returns true when the did document has the verification method with the id
+func HasVerificationMethod(document map[string]interface{}, methodId string) bool {
+	for _, method := range listOf(document["verificationMethod"]) {
+		if methodId != "" && verificationMethodId(method) == methodId {
+			return true
+		}
+	}
+	return false
+}
+
+// VerificationRelationships returns the relationships of the verification method with the id
+func VerificationRelationships(document map[string]interface{}, methodId string) []string {
+	did, _ := document["id"].(string)
+	relationships := []string{}
+	for _, relationship := range verificationRelationships {
+		for _, id := range listOf(document[relationship]) {
+			if id == methodId || fmt.Sprint(did, id) == methodId {
+				relationships = append(relationships, relationship)
+				break
+			}
+		}
+	}
+	return relationships
+}
+
+// VerificationMethodKey returns the public key of the verification method with the id, the id of the verification
+// method in the document is either absolute or relative to the did
+func VerificationMethodKey(document map[string]interface{}, methodId string) (jwk.Key, error) {
+	did, _ := document["id"].(string)
+	for _, method := range listOf(document["verificationMethod"]) {
+		id := verificationMethodId(method)
+		if id == "" || (id != methodId && did+id != methodId) {
+			continue
+		}
+		publicKeyJwk, found := method.(map[string]interface{})["publicKeyJwk"]
+		if !found {
+			return nil, fmt.Errorf("the verification method %s has no publicKeyJwk", methodId)
+		}
+		jwkBytes, err := json.Marshal(publicKeyJwk)
+		if err != nil {
+			return nil, err
+		}
+		return jwk.ParseKey(jwkBytes)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrVerificationMethodNotFound, methodId)
+}
+
+// RemoveVerificationMethod removes the verification method with the id from the did document and its relationships
+func RemoveVerificationMethod(document map[string]interface{}, methodId string) {
+	methods := []interface{}{}
+	for _, method := range listOf(document["verificationMethod"]) {
+		if verificationMethodId(method) != methodId {
+			methods = append(methods, method)
+		}
+	}
+	document["verificationMethod"] = methods
+	did, _ := document["id"].(string)
+	for _, relationship := range VerificationRelationships(document, methodId) {
+		ids := []interface{}{}
+		for _, id := range listOf(document[relationship]) {
+			if id != methodId && fmt.Sprint(did, id) != methodId {
+				ids = append(ids, id)
+			}
+		}
+		document[relationship] = ids
+	}
+}
+
+// verificationMethodId returns the id of the verification method
+func verificationMethodId(method interface{}) string {
+	if method, ok := method.(map[string]interface{}); ok {
+		id, _ := method["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// appendToList appends the value to the list of the document
+func appendToList(list interface{}, value interface{}) []interface{} {
+	return append(listOf(list), value)
+}
+
+// listOf returns a copy of the list of the document, the list is either created by NewDocument or decoded from json
+func listOf(list interface{}) []interface{} {
+	values := []interface{}{}
+	switch elements := list.(type) {
+	case []interface{}:
+		values = append(values, elements...)
+	case []map[string]interface{}:
+		for _, element := range elements {
+			values = append(values, element)
+		}
+	case []string:
+		for _, element := range elements {
+			values = append(values, element)
+		}
+	}
+	return values
+}
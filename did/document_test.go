@@ -0,0 +1,83 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPublicKey(t *testing.T, kid string) jwk.Key {
+	rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	publicKey, err := jwk.FromRaw(rawKey.Public())
+	require.NoError(t, err)
+	publicKey.Set(jwk.KeyIDKey, kid)
+	return publicKey
+}
+
+func TestDocument(t *testing.T) {
+	identifier := "did:web:example.org"
+	t.Run("AddVerificationMethod", func(t *testing.T) {
+		document := did.NewDocument(identifier, newPublicKey(t, identifier+"#key-1"))
+		did.AddVerificationMethod(document, identifier+"#key-2", newPublicKey(t, identifier+"#key-2"), "authentication", "capabilityInvocation")
+
+		assert.Len(t, document["verificationMethod"], 2)
+		assert.Equal(t, []interface{}{identifier + "#key-1", identifier + "#key-2"}, document["authentication"])
+		assert.Equal(t, []string{identifier + "#key-1"}, document["assertionMethod"])
+		assert.Equal(t, []interface{}{identifier + "#key-2"}, document["capabilityInvocation"])
+	})
+	t.Run("AddVerificationMethodToJson", func(t *testing.T) {
+		documentBytes, err := json.Marshal(did.NewDocument(identifier, newPublicKey(t, identifier+"#key-1")))
+		require.NoError(t, err)
+		document := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(documentBytes, &document))
+
+		did.AddVerificationMethod(document, identifier+"#key-2", newPublicKey(t, ""), "assertionMethod")
+		assert.Len(t, document["verificationMethod"], 2)
+		assert.Equal(t, []interface{}{identifier + "#key-1", identifier + "#key-2"}, document["assertionMethod"])
+	})
+	t.Run("RemoveVerificationMethod", func(t *testing.T) {
+		document := did.NewDocument(identifier, newPublicKey(t, identifier+"#key-1"), newPublicKey(t, identifier+"#key-2"))
+		assert.True(t, did.HasVerificationMethod(document, identifier+"#key-1"))
+		assert.Equal(t, []string{"authentication", "assertionMethod"}, did.VerificationRelationships(document, identifier+"#key-1"))
+
+		did.RemoveVerificationMethod(document, identifier+"#key-1")
+		assert.False(t, did.HasVerificationMethod(document, identifier+"#key-1"))
+		assert.Empty(t, did.VerificationRelationships(document, identifier+"#key-1"))
+		assert.Len(t, document["verificationMethod"], 1)
+		assert.Equal(t, []interface{}{identifier + "#key-2"}, document["authentication"])
+	})
+	t.Run("VerificationMethodKey", func(t *testing.T) {
+		publicKey := newPublicKey(t, identifier+"#key-1")
+		documentBytes, err := json.Marshal(did.NewDocument(identifier, publicKey))
+		require.NoError(t, err)
+		document := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(documentBytes, &document))
+
+		methodKey, err := did.VerificationMethodKey(document, identifier+"#key-1")
+		require.NoError(t, err)
+		assert.Equal(t, publicKey.KeyID(), methodKey.KeyID())
+		_, err = did.VerificationMethodKey(document, identifier+"#key-2")
+		assert.ErrorIs(t, err, did.ErrVerificationMethodNotFound)
+	})
+	t.Run("RelativeMethodId", func(t *testing.T) {
+		document := map[string]interface{}{
+			"id":                 identifier,
+			"verificationMethod": []interface{}{map[string]interface{}{"id": "#key-1", "publicKeyJwk": newPublicKey(t, "")}},
+			"assertionMethod":    []interface{}{"#key-1"},
+		}
+		_, err := did.VerificationMethodKey(document, identifier+"#key-1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"assertionMethod"}, did.VerificationRelationships(document, identifier+"#key-1"))
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did
+
+import (
+	"crypto"
+	"encoding/base64"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/multiformats/go-multibase"
+)
+
+const (
+	ebsiMethodPrefix string = "did:ebsi:"
+	// version byte of the ebsi method specific identifier of a natural person
+	ebsiNaturalPersonVersion byte = 0x02
+)
+
+// NewEbsiNaturalPerson returns the ebsi identifier of a natural person, which is derived from the
+// jwk thumbprint of the public key of the holder
+// See the specs at https://ec.europa.eu/digital-building-blocks/wikis/display/EBSIDOC/EBSI+DID+Method
+func NewEbsiNaturalPerson(publicKey jwk.Key) (string, error) {
+	thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	methodSpecificId, err := multibase.Encode(multibase.Base58BTC, append([]byte{ebsiNaturalPersonVersion}, thumbprint...))
+	if err != nil {
+		return "", err
+	}
+	return ebsiMethodPrefix + methodSpecificId, nil
+}
+
+// EbsiNaturalPersonKeyId returns the key id of the public key of a natural person
+func EbsiNaturalPersonKeyId(did string, publicKey jwk.Key) (string, error) {
+	thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return did + "#" + base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// IsEbsi returns true when the identifier uses the ebsi method
+func IsEbsi(did string) bool {
+	return strings.HasPrefix(did, ebsiMethodPrefix)
+}
+
+// IsEbsiNaturalPerson returns true when the ebsi identifier is the identifier of a natural person
+func IsEbsiNaturalPerson(did string) bool {
+	if !IsEbsi(did) {
+		return false
+	}
+	_, decoded, err := multibase.Decode(strings.TrimPrefix(did, ebsiMethodPrefix))
+	if err != nil || len(decoded) == 0 {
+		return false
+	}
+	return decoded[0] == ebsiNaturalPersonVersion
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/did"
+	"github.com/gossif/ebsi"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEbsi(t *testing.T) {
+	t.Run("NaturalPerson", func(t *testing.T) {
+		rawKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		publicKey, _ := jwk.FromRaw(rawKey.Public())
+
+		identifier, err := did.NewEbsiNaturalPerson(publicKey)
+		assert.NoError(t, err)
+		assert.True(t, did.IsEbsiNaturalPerson(identifier))
+
+		ebsiIdentifier := ebsi.NewDecentralizedIdentifier()
+		assert.NoError(t, ebsiIdentifier.ParseIdentifier(identifier))
+
+		kid, err := did.EbsiNaturalPersonKeyId(identifier, publicKey)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(kid, identifier+"#"))
+	})
+	t.Run("LegalEntity", func(t *testing.T) {
+		ebsiIdentifier := ebsi.NewDecentralizedIdentifier()
+		ebsiIdentifier.GenerateMethodSpecificId()
+
+		assert.True(t, did.IsEbsi(ebsiIdentifier.String()))
+		assert.False(t, did.IsEbsiNaturalPerson(ebsiIdentifier.String()))
+	})
+}
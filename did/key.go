@@ -0,0 +1,129 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	secp256k1v4 "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/multiformats/go-multibase"
+)
+
+const (
+	keyMethodPrefix string = "did:key:"
+	// multicodec codes of the compressed public keys
+	// See https://github.com/multiformats/multicodec/blob/master/table.csv
+	codecP256Pub      uint64 = 0x1200
+	codecSecp256k1Pub uint64 = 0xe7
+)
+
+const (
+	KeyTypeP256      string = "P-256"
+	KeyTypeSecp256k1 string = "secp256k1"
+)
+
+// NewKey returns the did:key identifier of the public key
+// See the specs at https://w3c-ccg.github.io/did-method-key/
+func NewKey(publicKey jwk.Key) (string, error) {
+	methodSpecificId, err := MultibasePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return keyMethodPrefix + methodSpecificId, nil
+}
+
+// MultibasePublicKey returns the base58btc multibase encoding of the multicodec compressed public key
+func MultibasePublicKey(publicKey jwk.Key) (string, error) {
+	var (
+		rawKey ecdsa.PublicKey
+		codec  uint64
+	)
+	ecKey, ok := publicKey.(jwk.ECDSAPublicKey)
+	if !ok {
+		return "", errors.New("unsupported key type")
+	}
+	if err := ecKey.Raw(&rawKey); err != nil {
+		return "", err
+	}
+	switch ecKey.Crv() {
+	case jwa.P256:
+		codec = codecP256Pub
+	case jwa.EllipticCurveAlgorithm(KeyTypeSecp256k1):
+		codec = codecSecp256k1Pub
+	default:
+		return "", errors.New("unsupported curve")
+	}
+	codecBytes := binary.AppendUvarint(nil, codec)
+	compressed := elliptic.MarshalCompressed(rawKey.Curve, rawKey.X, rawKey.Y)
+	return multibase.Encode(multibase.Base58BTC, append(codecBytes, compressed...))
+}
+
+// IsKey returns true when the identifier uses the key method
+func IsKey(did string) bool {
+	return strings.HasPrefix(did, keyMethodPrefix)
+}
+
+// KeyVerificationMethodId returns the id of the verification method of the did:key identifier
+func KeyVerificationMethodId(did string) string {
+	return did + "#" + strings.TrimPrefix(did, keyMethodPrefix)
+}
+
+// KeyPublicKey returns the public key encoded in the did:key identifier
+func KeyPublicKey(did string) (jwk.Key, error) {
+	var (
+		rawKey *ecdsa.PublicKey
+	)
+	if !IsKey(did) {
+		return nil, errors.New("unsupported_method")
+	}
+	encoding, keyBytes, err := multibase.Decode(strings.TrimPrefix(did, keyMethodPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if encoding != multibase.Base58BTC {
+		return nil, errors.New("invalid_encoding")
+	}
+	codec, n := binary.Uvarint(keyBytes)
+	if n <= 0 {
+		return nil, errors.New("invalid_multicodec")
+	}
+	switch codec {
+	case codecP256Pub:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), keyBytes[n:])
+		if x == nil {
+			return nil, errors.New("invalid_public_key")
+		}
+		rawKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case codecSecp256k1Pub:
+		pubKey, err := secp256k1v4.ParsePubKey(keyBytes[n:])
+		if err != nil {
+			return nil, err
+		}
+		rawKey = pubKey.ToECDSA()
+	default:
+		return nil, fmt.Errorf("unsupported multicodec: %#x", codec)
+	}
+	publicKey, err := jwk.FromRaw(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey.Set(jwk.KeyIDKey, KeyVerificationMethodId(did))
+	return publicKey, nil
+}
+
+// ResolveKey returns the did document of the did:key identifier, the document is derived from the identifier
+func ResolveKey(did string) (map[string]interface{}, error) {
+	publicKey, err := KeyPublicKey(did)
+	if err != nil {
+		return nil, err
+	}
+	return NewDocument(did, publicKey), nil
+}
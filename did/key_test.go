@@ -0,0 +1,52 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	t.Run("NewKey", func(t *testing.T) {
+		rawKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		expectedKey, _ := jwk.FromRaw(rawKey.Public())
+
+		identifier, err := did.NewKey(expectedKey)
+		assert.NoError(t, err)
+		// the multicodec prefix of a compressed p-256 public key encodes as zDn
+		assert.True(t, strings.HasPrefix(identifier, "did:key:zDn"))
+
+		actualKey, err := did.KeyPublicKey(identifier)
+		assert.NoError(t, err)
+		expectedThumbprint, _ := expectedKey.Thumbprint(crypto.SHA256)
+		actualThumbprint, _ := actualKey.Thumbprint(crypto.SHA256)
+		assert.Equal(t, expectedThumbprint, actualThumbprint)
+		assert.Equal(t, did.KeyVerificationMethodId(identifier), actualKey.KeyID())
+	})
+	t.Run("ResolveKey", func(t *testing.T) {
+		// test vector of https://w3c-ccg.github.io/did-method-key/#p-256
+		var (
+			expectedDid string = "did:key:zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169"
+		)
+		document, err := did.ResolveKey(expectedDid)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedDid, document["id"])
+		assert.Equal(t, []string{expectedDid + "#zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169"}, document["assertionMethod"])
+	})
+	t.Run("InvalidIdentifier", func(t *testing.T) {
+		_, err := did.ResolveKey("did:ebsi:zfEmvX5twhXjQJiCWsukvQA")
+		assert.Error(t, err)
+		_, err = did.ResolveKey("did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const webMethodPrefix string = "did:web:"
+
+// NewWeb returns the did:web identifier of the domain with an optional path, f.e. example.org:user:alice
+// or example.org/user/alice. A port must be percent encoded, f.e. localhost%3A8443.
+// See the specs at https://w3c-ccg.github.io/did-method-web/
+func NewWeb(domain string) (string, error) {
+	domain = strings.TrimPrefix(domain, "https://")
+	segments := strings.FieldsFunc(domain, func(r rune) bool { return r == '/' || r == ':' })
+	if len(segments) == 0 {
+		return "", errors.New("invalid_domain")
+	}
+	host, err := url.PathUnescape(segments[0])
+	if err != nil || strings.TrimSpace(host) == "" || strings.ContainsAny(host, "/?#@ ") {
+		return "", errors.New("invalid_domain")
+	}
+	identifier := webMethodPrefix + strings.Replace(strings.ToLower(host), ":", "%3A", 1)
+	for _, segment := range segments[1:] {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil || unescaped == "." || unescaped == ".." || strings.Contains(unescaped, "/") {
+			return "", errors.New("invalid_path")
+		}
+		identifier += ":" + url.PathEscape(unescaped)
+	}
+	return identifier, nil
+}
+
+// IsWeb returns true when the identifier uses the web method
+func IsWeb(did string) bool {
+	return strings.HasPrefix(did, webMethodPrefix)
+}
+
+// WebDocumentPath returns the path of the did document relative to the root of the web server
+func WebDocumentPath(did string) (string, error) {
+	if !IsWeb(did) {
+		return "", errors.New("unsupported_method")
+	}
+	segments := strings.Split(strings.TrimPrefix(did, webMethodPrefix), ":")
+	if len(segments) == 1 {
+		return path.Join(".well-known", "did.json"), nil
+	}
+	for i, segment := range segments[1:] {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil || unescaped == "." || unescaped == ".." || strings.Contains(unescaped, "/") {
+			return "", errors.New("invalid_identifier")
+		}
+		segments[i+1] = unescaped
+	}
+	return path.Join(append(segments[1:], "did.json")...), nil
+}
+
+// WebDocumentUrl returns the url where the did document of the did:web identifier is hosted
+func WebDocumentUrl(did string) (string, error) {
+	documentPath, err := WebDocumentPath(did)
+	if err != nil {
+		return "", err
+	}
+	host, err := url.PathUnescape(strings.Split(strings.TrimPrefix(did, webMethodPrefix), ":")[0])
+	if err != nil {
+		return "", errors.New("invalid_identifier")
+	}
+	return (&url.URL{Scheme: "https", Host: host, Path: "/" + documentPath}).String(), nil
+}
+
+// ResolveWeb fetches the did document of the did:web identifier from the web server
+func ResolveWeb(client *http.Client, did string) (map[string]interface{}, error) {
+	documentUrl, err := WebDocumentUrl(did)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Get(documentUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request_failed: %s %s", documentUrl, response.Status)
+	}
+	document := map[string]interface{}{}
+	if err = json.NewDecoder(response.Body).Decode(&document); err != nil {
+		return nil, err
+	}
+	if document["id"] != did {
+		return nil, fmt.Errorf("the did document of %s has the id %v", did, document["id"])
+	}
+	return document, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package did_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeb(t *testing.T) {
+	t.Run("NewWeb", func(t *testing.T) {
+		testCases := map[string]string{
+			"example.org":                 "did:web:example.org",
+			"https://Example.org/":        "did:web:example.org",
+			"example.org:user:alice":      "did:web:example.org:user:alice",
+			"example.org/user/alice":      "did:web:example.org:user:alice",
+			"localhost%3A8443":            "did:web:localhost%3A8443",
+			"localhost%3A8443/user/alice": "did:web:localhost%3A8443:user:alice",
+		}
+		for domain, expectedDid := range testCases {
+			actualDid, err := did.NewWeb(domain)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedDid, actualDid)
+		}
+	})
+	t.Run("InvalidDomain", func(t *testing.T) {
+		for _, domain := range []string{"", "/", "example.org:..", "user@example.org", "example.org:a%2F.."} {
+			_, err := did.NewWeb(domain)
+			assert.Error(t, err, domain)
+		}
+	})
+	t.Run("WebDocumentUrl", func(t *testing.T) {
+		testCases := map[string]string{
+			"did:web:example.org":                 "https://example.org/.well-known/did.json",
+			"did:web:example.org:user:alice":      "https://example.org/user/alice/did.json",
+			"did:web:localhost%3A8443:user:alice": "https://localhost:8443/user/alice/did.json",
+		}
+		for identifier, expectedUrl := range testCases {
+			actualUrl, err := did.WebDocumentUrl(identifier)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedUrl, actualUrl)
+		}
+	})
+	t.Run("WebDocumentPath", func(t *testing.T) {
+		actualPath, err := did.WebDocumentPath("did:web:example.org")
+		assert.NoError(t, err)
+		assert.Equal(t, ".well-known/did.json", actualPath)
+
+		_, err = did.WebDocumentPath("did:web:example.org:..:etc")
+		assert.Error(t, err)
+	})
+	t.Run("ResolveWeb", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := strings.Replace(r.Host, ":", "%3A", 1)
+			switch r.URL.Path {
+			case "/.well-known/did.json":
+				fmt.Fprintf(w, `{"id": "did:web:%s"}`, host)
+			case "/other/did.json":
+				fmt.Fprint(w, `{"id": "did:web:example.org"}`)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+		host := strings.Replace(strings.TrimPrefix(server.URL, "https://"), ":", "%3A", 1)
+
+		document, err := did.ResolveWeb(server.Client(), "did:web:"+host)
+		require.NoError(t, err)
+		assert.Equal(t, "did:web:"+host, document["id"])
+		_, err = did.ResolveWeb(server.Client(), "did:web:"+host+":other")
+		assert.Error(t, err)
+		_, err = did.ResolveWeb(server.Client(), "did:web:"+host+":unknown")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,315 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package ebsiapi
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gossif/admin/did"
+	"github.com/gossif/ebsi/secp256k1"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	DefaultScope string = "openid did_authn"
+	siopIssuer   string = "https://self-issued.me/v2"
+)
+
+// AccessToken is the access token of the authorisation api
+type AccessToken struct {
+	AccessToken string
+	Scope       string
+	ExpiresAt   time.Time
+}
+
+// authenticationRequest are the parameters of the siop authentication request
+type authenticationRequest struct {
+	ClientId string
+	Nonce    string
+}
+
+// ake1SigPayload is the signed part of the siop session response
+type ake1SigPayload struct {
+	IssuedAt         int64  `json:"iat"`
+	ExpirationTime   int64  `json:"exp"`
+	Nonce            string `json:"ake1_nonce"`
+	EncryptedPayload string `json:"ake1_enc_payload"`
+	Did              string `json:"did"`
+	Issuer           string `json:"iss"`
+}
+
+// ake1Payload is the response of the siop session, the access token is encrypted for the encryption key. The signed
+// payload is kept as received, it is the detached payload of the jws.
+type ake1Payload struct {
+	EncryptedPayload string          `json:"ake1_enc_payload"`
+	SignedPayload    json.RawMessage `json:"ake1_sig_payload"`
+	JwsDetached      string          `json:"ake1_jws_detached"`
+	Did              string          `json:"did"`
+}
+
+// ake1Decrypted is the decrypted payload of the siop session
+type ake1Decrypted struct {
+	AccessToken string `json:"access_token"`
+	Nonce       string `json:"nonce"`
+	Did         string `json:"did"`
+}
+
+// AccessToken performs the did siop flow of the authorisation api and returns the access token
+// See https://ec.europa.eu/digital-building-blocks/wikis/display/EBSIDOC/Authorisation+API
+// The verifiable authorisation is the credential received when onboarding the did, the response of the siop session
+// must be signed by its issuer.
+func (c *Client) AccessToken(did string, scope string, verifiableAuthorisation string, signingKey, encryptionKey jwk.Key) (*AccessToken, error) {
+	if strings.TrimSpace(did) == "" {
+		return nil, errors.New("missing_did")
+	}
+	if strings.TrimSpace(verifiableAuthorisation) == "" {
+		return nil, errors.New("missing_token:verifiableAuthorization")
+	}
+	if signingKey == nil {
+		return nil, errors.New("missing_signing_key")
+	}
+	if encryptionKey == nil {
+		return nil, errors.New("missing_encryption_key")
+	}
+	if strings.TrimSpace(scope) == "" {
+		scope = DefaultScope
+	}
+	authorisationDid, err := authorisationIssuer(verifiableAuthorisation)
+	if err != nil {
+		return nil, err
+	}
+	authRequest, err := c.postAuthenticationRequest(scope)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := generateIdToken(did, authRequest, signingKey, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	vpToken, err := generateVpToken(did, authRequest, verifiableAuthorisation, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	sessionResponse := ake1Payload{}
+	if err = c.httpPost("/authorisation/v2/siop-sessions", "", map[string]string{"id_token": string(idToken), "vp_token": string(vpToken)}, &sessionResponse); err != nil {
+		return nil, err
+	}
+	signature, err := c.verifyAke1Signature(&sessionResponse, authorisationDid)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := decryptAke1Payload(&sessionResponse, signature, authRequest.Nonce, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if decrypted.Did != did {
+		return nil, errors.New("did of the access token is not equal to the did of the request")
+	}
+	return &AccessToken{
+		AccessToken: decrypted.AccessToken,
+		Scope:       scope,
+		ExpiresAt:   accessTokenExpiry(decrypted.AccessToken, signature.ExpirationTime),
+	}, nil
+}
+
+// postAuthenticationRequest starts the siop authentication request and returns the client id and nonce
+func (c *Client) postAuthenticationRequest(scope string) (authenticationRequest, error) {
+	response := map[string]interface{}{}
+	if err := c.httpPost("/authorisation/v2/authentication-requests", "", map[string]string{"scope": scope}, &response); err != nil {
+		return authenticationRequest{}, err
+	}
+	uri, _ := response["uri"].(string)
+	_, rawQuery, found := strings.Cut(uri, "?")
+	if !found {
+		return authenticationRequest{}, errors.New("invalid_authentication_request")
+	}
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return authenticationRequest{}, err
+	}
+	if params.Get("nonce") == "" {
+		return authenticationRequest{}, errors.New("invalid_authentication_request: missing nonce")
+	}
+	return authenticationRequest{ClientId: params.Get("client_id"), Nonce: params.Get("nonce")}, nil
+}
+
+// generateIdToken generates the self-issued id token with the public encryption key as claim
+func generateIdToken(did string, authRequest authenticationRequest, signingKey, encryptionKey jwk.Key) ([]byte, error) {
+	publicEncKey, err := encryptionKey.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	publicSigKey, err := signingKey.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := signingKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := jwt.NewBuilder().
+		Issuer(siopIssuer).
+		Audience([]string{authRequest.audience()}).
+		Subject(base64.RawURLEncoding.EncodeToString(thumbprint)).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(time.Minute*5)).
+		Claim("did", did).
+		Claim("nonce", authRequest.Nonce).
+		Claim("sub_jwk", publicSigKey).
+		Claim("claims", map[string]interface{}{"encryption_key": publicEncKey}).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Sign(idToken, jwt.WithKey(jwa.ES256K, signingKey))
+}
+
+// generateVpToken wraps the verifiable authorisation in a presentation signed by the did
+func generateVpToken(did string, authRequest authenticationRequest, verifiableAuthorisation string, signingKey jwk.Key) ([]byte, error) {
+	presentation := map[string]interface{}{
+		"@context":             []string{"https://www.w3.org/2018/credentials/v1"},
+		"type":                 []string{"VerifiablePresentation"},
+		"holder":               did,
+		"verifiableCredential": []string{verifiableAuthorisation},
+	}
+	vpToken, err := jwt.NewBuilder().
+		Issuer(did).
+		JwtID(fmt.Sprintf("urn:uuid:%s", uuid.NewString())).
+		Audience([]string{authRequest.audience()}).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(time.Minute*5)).
+		Claim("nonce", authRequest.Nonce).
+		Claim("vp", presentation).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Sign(vpToken, jwt.WithKey(jwa.ES256K, signingKey))
+}
+
+// audience returns the client id of the authentication request, defaults to the siop sessions endpoint
+func (a authenticationRequest) audience() string {
+	if strings.TrimSpace(a.ClientId) == "" {
+		return "/siop-sessions"
+	}
+	return a.ClientId
+}
+
+// authorisationIssuer returns the did of the issuer of the verifiable authorisation
+func authorisationIssuer(verifiableAuthorisation string) (string, error) {
+	token, err := jwt.ParseInsecure([]byte(verifiableAuthorisation))
+	if err != nil {
+		return "", fmt.Errorf("invalid_token:verifiableAuthorization: %w", err)
+	}
+	if strings.TrimSpace(token.Issuer()) == "" {
+		return "", errors.New("invalid_token:verifiableAuthorization: missing issuer")
+	}
+	return token.Issuer(), nil
+}
+
+// verifyAke1Signature verifies the detached jws of the signed payload with a verification method of the issuer of
+// the verifiable authorisation and returns the signed payload
+func (c *Client) verifyAke1Signature(sessionResponse *ake1Payload, authorisationDid string) (ake1SigPayload, error) {
+	signature := ake1SigPayload{}
+	if len(sessionResponse.SignedPayload) == 0 || strings.TrimSpace(sessionResponse.JwsDetached) == "" {
+		return signature, errors.New("invalid_ake1: missing signature")
+	}
+	message, err := jws.Parse([]byte(sessionResponse.JwsDetached))
+	if err != nil || len(message.Signatures()) != 1 {
+		return signature, fmt.Errorf("invalid_ake1: invalid jws: %v", err)
+	}
+	headers := message.Signatures()[0].ProtectedHeaders()
+	if signer, _, _ := strings.Cut(headers.KeyID(), "#"); signer != authorisationDid {
+		return signature, fmt.Errorf("invalid_ake1: the kid %s isn't a key of %s", headers.KeyID(), authorisationDid)
+	}
+	if headers.Algorithm() != jwa.ES256 && headers.Algorithm() != jwa.ES256K {
+		return signature, fmt.Errorf("invalid_ake1: unsupported algorithm %s", headers.Algorithm())
+	}
+	document, err := c.resolveDocument(authorisationDid)
+	if err != nil {
+		return signature, err
+	}
+	publicKey, err := did.VerificationMethodKey(document, headers.KeyID())
+	if err != nil {
+		return signature, fmt.Errorf("invalid_ake1: %w", err)
+	}
+	if _, err = jws.Verify([]byte(sessionResponse.JwsDetached), jws.WithKey(headers.Algorithm(), publicKey), jws.WithDetachedPayload(sessionResponse.SignedPayload)); err != nil {
+		return signature, fmt.Errorf("invalid_ake1: %w", err)
+	}
+	if err = json.Unmarshal(sessionResponse.SignedPayload, &signature); err != nil {
+		return signature, fmt.Errorf("invalid_ake1: %w", err)
+	}
+	return signature, nil
+}
+
+// resolveDocument returns the did document, a did:key is derived from the identifier and other dids are resolved
+// with the did registry
+func (c *Client) resolveDocument(identifier string) (map[string]interface{}, error) {
+	if did.IsKey(identifier) {
+		return did.ResolveKey(identifier)
+	}
+	document := map[string]interface{}{}
+	if err := c.httpGet("/did-registry/v3/identifiers/"+url.PathEscape(identifier), &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// decryptAke1Payload decrypts the siop session response with the encryption key, the signed and encrypted nonce
+// must be the nonce of the id token
+func decryptAke1Payload(sessionResponse *ake1Payload, signature ake1SigPayload, nonce string, encryptionKey jwk.Key) (*ake1Decrypted, error) {
+	decrypted := ake1Decrypted{}
+	if signature.EncryptedPayload != sessionResponse.EncryptedPayload {
+		return nil, errors.New("encrypted payload is not signed")
+	}
+	if signature.Nonce != nonce {
+		return nil, errors.New("nonce signed is not equal to nonce send")
+	}
+	ciphertext, err := hex.DecodeString(sessionResponse.EncryptedPayload)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := secp256k1.NewPrivateKeyFromJwk(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := privateKey.Decrypt(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, err
+	}
+	if decrypted.Nonce != nonce {
+		return nil, errors.New("nonce encrypted is not equal to nonce send")
+	}
+	if sessionResponse.Did != decrypted.Did {
+		return nil, errors.New("did encrypted is not equal to did received in ake1")
+	}
+	return &decrypted, nil
+}
+
+// accessTokenExpiry returns the expiry of the access token, the signed ake1 expiry is used when the
+// access token isn't a jwt
+func accessTokenExpiry(accessToken string, ake1Expiry int64) time.Time {
+	if token, err := jwt.ParseInsecure([]byte(accessToken)); err == nil && !token.Expiration().IsZero() {
+		return token.Expiration()
+	}
+	if ake1Expiry > 0 {
+		return time.Unix(ake1Expiry, 0)
+	}
+	return time.Now().Add(time.Minute * 15)
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build jwx_es256k
+
+package ebsiapi_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/did"
+	"github.com/gossif/admin/ebsiapi"
+	"github.com/gossif/ebsi/secp256k1"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDid   string = "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"
+	testNonce string = "b5e5a3b6-3d63-4a0e-8d34-1d0b3b1e8f7a"
+)
+
+// newIssuerKey generates the P-256 key of a did:key issuer, the kid is the verification method of the did
+func newIssuerKey(t *testing.T) (string, jwk.Key) {
+	rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.FromRaw(rawKey)
+	require.NoError(t, err)
+	publicKey, _ := key.PublicKey()
+	issuer, err := did.NewKey(publicKey)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, did.KeyVerificationMethodId(issuer)))
+	return issuer, key
+}
+
+// newAuthorisationServer signs the ake1 payload with the key and the nonce, the nonce of the id token when empty
+func newAuthorisationServer(t *testing.T, expiresAt time.Time, signingKey jwk.Key, ake1Nonce string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorisation/v2/authentication-requests", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"uri": "openid://?response_type=id_token&client_id=https%3A%2F%2Fapi.example.org%2Fsiop-sessions&scope=openid+did_authn&nonce=" + testNonce,
+		})
+	})
+	mux.HandleFunc("/authorisation/v2/siop-sessions", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			publicEncKey ecdsa.PublicKey
+		)
+		request := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&request)
+		idToken, err := jwt.ParseInsecure([]byte(request["id_token"]))
+		assert.NoError(t, err)
+		nonce, _ := idToken.Get("nonce")
+		assert.Equal(t, testNonce, nonce)
+		assert.Equal(t, []string{"https://api.example.org/siop-sessions"}, idToken.Audience())
+
+		claims, _ := idToken.Get("claims")
+		claimBytes, _ := json.Marshal(claims.(map[string]interface{})["encryption_key"])
+		encKey, err := jwk.ParseKey(claimBytes)
+		assert.NoError(t, err)
+		assert.NoError(t, encKey.Raw(&publicEncKey))
+
+		accessToken, _ := jwt.NewBuilder().Subject(testDid).Expiration(expiresAt).Build()
+		serializedToken, _ := jwt.NewSerializer().Serialize(accessToken)
+		responseNonce := ake1Nonce
+		if responseNonce == "" {
+			responseNonce = nonce.(string)
+		}
+		plaintext, _ := json.Marshal(map[string]string{"access_token": string(serializedToken), "nonce": responseNonce, "did": testDid})
+		ephemeralKey, _ := secp256k1.GeneratePrivateKey()
+		ciphertext, err := ephemeralKey.Encrypt(&publicEncKey, plaintext)
+		assert.NoError(t, err)
+		sigPayload, _ := json.Marshal(map[string]interface{}{"ake1_nonce": responseNonce, "ake1_enc_payload": hex.EncodeToString(ciphertext), "did": testDid})
+		jwsDetached, err := jws.Sign(nil, jws.WithKey(jwa.ES256, signingKey), jws.WithDetachedPayload(sigPayload))
+		assert.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ake1_enc_payload":  hex.EncodeToString(ciphertext),
+			"ake1_sig_payload":  json.RawMessage(sigPayload),
+			"ake1_jws_detached": string(jwsDetached),
+			"did":               testDid,
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestAccessToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute * 15).Truncate(time.Second)
+	issuer, issuerKey := newIssuerKey(t)
+	server := newAuthorisationServer(t, expiresAt, issuerKey, "")
+	defer server.Close()
+
+	authorisationToken, _ := jwt.NewBuilder().Issuer(issuer).Subject(testDid).Build()
+	verifiableAuthorisation, err := jwt.Sign(authorisationToken, jwt.WithKey(jwa.ES256, issuerKey))
+	require.NoError(t, err)
+	signingKey, _ := secp256k1.GeneratePrivateKeyAsJwk(testDid)
+	encryptionKey, _ := secp256k1.GeneratePrivateKeyAsJwk(testDid)
+	client := ebsiapi.NewClient(ebsiapi.WithBaseUrl(server.URL))
+
+	t.Run("AccessToken", func(t *testing.T) {
+		accessToken, err := client.AccessToken(testDid, "", string(verifiableAuthorisation), signingKey, encryptionKey)
+		require.NoError(t, err)
+		assert.Equal(t, ebsiapi.DefaultScope, accessToken.Scope)
+		assert.True(t, expiresAt.Equal(accessToken.ExpiresAt))
+	})
+	t.Run("ForeignNonce", func(t *testing.T) {
+		server := newAuthorisationServer(t, expiresAt, issuerKey, "ake1-nonce")
+		defer server.Close()
+		client := ebsiapi.NewClient(ebsiapi.WithBaseUrl(server.URL))
+		_, err := client.AccessToken(testDid, "", string(verifiableAuthorisation), signingKey, encryptionKey)
+		assert.ErrorContains(t, err, "nonce signed is not equal to nonce send")
+	})
+	t.Run("ForeignSigner", func(t *testing.T) {
+		_, otherKey := newIssuerKey(t)
+		server := newAuthorisationServer(t, expiresAt, otherKey, "")
+		defer server.Close()
+		client := ebsiapi.NewClient(ebsiapi.WithBaseUrl(server.URL))
+		_, err := client.AccessToken(testDid, "", string(verifiableAuthorisation), signingKey, encryptionKey)
+		assert.ErrorContains(t, err, "invalid_ake1")
+
+		// the kid of the issuer with another key
+		require.NoError(t, otherKey.Set(jwk.KeyIDKey, issuerKey.KeyID()))
+		server = newAuthorisationServer(t, expiresAt, otherKey, "")
+		defer server.Close()
+		client = ebsiapi.NewClient(ebsiapi.WithBaseUrl(server.URL))
+		_, err = client.AccessToken(testDid, "", string(verifiableAuthorisation), signingKey, encryptionKey)
+		assert.ErrorContains(t, err, "invalid_ake1")
+	})
+	t.Run("NotOnboarded", func(t *testing.T) {
+		_, err := client.AccessToken(testDid, "", "", signingKey, encryptionKey)
+		assert.Error(t, err)
+	})
+	t.Run("APIError", func(t *testing.T) {
+		client := ebsiapi.NewClient(ebsiapi.WithBaseUrl(server.URL + "/unknown"))
+		_, err := client.AccessToken(testDid, "", string(verifiableAuthorisation), signingKey, encryptionKey)
+		apiError := &ebsiapi.APIError{}
+		assert.ErrorAs(t, err, &apiError)
+		assert.Equal(t, http.StatusNotFound, apiError.StatusCode)
+	})
+}
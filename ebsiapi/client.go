@@ -0,0 +1,102 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package ebsiapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client is a client of the ebsi apis that are not covered by the ebsi package
+type Client struct {
+	hasBaseUrl    string
+	hasHttpClient *http.Client
+}
+
+type clientOption func(*Client)
+
+// WithBaseUrl sets the option of base url of the ebsi apis
+func WithBaseUrl(baseUrl string) clientOption {
+	return func(c *Client) {
+		c.hasBaseUrl = strings.TrimSuffix(baseUrl, "/")
+	}
+}
+
+// WithHttpClient sets the option of the http client
+func WithHttpClient(httpClient *http.Client) clientOption {
+	return func(c *Client) {
+		c.hasHttpClient = httpClient
+	}
+}
+
+func NewClient(options ...clientOption) *Client {
+	c := &Client{
+		hasBaseUrl:    "https://api-pilot.ebsi.eu",
+		hasHttpClient: http.DefaultClient,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the ebsi api responds with an unexpected http status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if strings.TrimSpace(e.Body) == "" {
+		return fmt.Sprintf("request_failed: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("request_failed: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// httpPost posts the payload as json and decodes the json response into the result
+func (c *Client) httpPost(path string, authToken string, payload interface{}, result interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.hasBaseUrl+path, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(authToken) != "" {
+		req.Header.Add("Authorization", "Bearer "+authToken)
+	}
+	req.Header.Add("Content-Type", "application/json; charset=utf-8")
+	return c.do(req, result)
+}
+
+// httpGet gets the path and decodes the json response into the result
+func (c *Client) httpGet(path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.hasBaseUrl+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	return c.do(req, result)
+}
+
+func (c *Client) do(req *http.Request, result interface{}) error {
+	resp, err := c.hasHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return json.NewDecoder(resp.Body).Decode(result)
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+}
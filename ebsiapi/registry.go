@@ -0,0 +1,334 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package ebsiapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gossif/ebsi/jcs"
+	"github.com/gossif/ebsi/secp256k1"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+const registryPath string = "/did-registry/v3/jsonrpc"
+
+// RPCError is returned when the json-rpc api responds with an error
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc_failed: %d %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	Id      int           `json:"id"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type unsignedTransaction struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Data     string `json:"data"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	ChainId  string `json:"chainId"`
+	GasLimit string `json:"gasLimit"`
+	GasPrice string `json:"gasPrice"`
+}
+
+type signedTransactionParams struct {
+	Protocol             string              `json:"protocol"`
+	UnsignedTransaction  unsignedTransaction `json:"unsignedTransaction"`
+	SignatureR           string              `json:"r"`
+	SignatureS           string              `json:"s"`
+	SignatureV           string              `json:"v"`
+	SignedRawTransaction string              `json:"signedRawTransaction"`
+}
+
+type addVerificationMethodParams struct {
+	From        string `json:"from"`
+	Did         string `json:"did"`
+	VMethodId   string `json:"vMethodId"`
+	PublicKey   string `json:"publicKey"`
+	IsSecp256k1 bool   `json:"isSecp256k1"`
+}
+
+type addVerificationRelationshipParams struct {
+	From         string `json:"from"`
+	Did          string `json:"did"`
+	Relationship string `json:"relationship"`
+	VMethodId    string `json:"vMethodId"`
+	NotBefore    int64  `json:"notBefore"`
+	NotAfter     int64  `json:"notAfter"`
+}
+
+type documentParams struct {
+	From               string `json:"from"`
+	Identifier         string `json:"identifier"`
+	HashAlgorithmId    int    `json:"hashAlgorithmId"`
+	HashValue          string `json:"hashValue"`
+	DidVersionInfo     string `json:"didVersionInfo"`
+	TimestampData      string `json:"timestampData"`
+	DidVersionMetadata string `json:"didVersionMetadata"`
+}
+
+type revokeVerificationMethodParams struct {
+	From      string `json:"from"`
+	Did       string `json:"did"`
+	VMethodId string `json:"vMethodId"`
+	NotAfter  int64  `json:"notAfter"`
+}
+
+// AddVerificationMethod adds the public key as verification method to the did document in the did registry,
+// the transaction is signed with the transaction key of the controller. It returns the transaction hash.
+func (c *Client) AddVerificationMethod(accessToken string, did string, vMethodId string, publicKey jwk.Key, transactionKey jwk.Key) (string, error) {
+	from, err := transactionAddress(transactionKey)
+	if err != nil {
+		return "", err
+	}
+	encodedKey, isSecp256k1, err := encodePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return c.sendRegistryTransaction(accessToken, "addVerificationMethod", addVerificationMethodParams{
+		From:        from,
+		Did:         did,
+		VMethodId:   VerificationMethodId(vMethodId),
+		PublicKey:   encodedKey,
+		IsSecp256k1: isSecp256k1,
+	}, transactionKey)
+}
+
+// AddVerificationRelationship adds the verification method to the relationship (f.e. authentication) of the did
+// document in the did registry, the relationship is valid from now till notAfter, a zero notAfter never expires.
+// It returns the transaction hash.
+func (c *Client) AddVerificationRelationship(accessToken string, did string, relationship string, vMethodId string, notAfter time.Time, transactionKey jwk.Key) (string, error) {
+	var (
+		notAfterUnix int64
+	)
+	from, err := transactionAddress(transactionKey)
+	if err != nil {
+		return "", err
+	}
+	if !notAfter.IsZero() {
+		notAfterUnix = notAfter.Unix()
+	}
+	return c.sendRegistryTransaction(accessToken, "addVerificationRelationship", addVerificationRelationshipParams{
+		From:         from,
+		Did:          did,
+		Relationship: relationship,
+		VMethodId:    VerificationMethodId(vMethodId),
+		NotBefore:    time.Now().Unix(),
+		NotAfter:     notAfterUnix,
+	}, transactionKey)
+}
+
+// RevokeVerificationMethod revokes the verification method of the did document in the did registry, the method
+// expires at notAfter. It returns the transaction hash.
+func (c *Client) RevokeVerificationMethod(accessToken string, did string, vMethodId string, notAfter time.Time, transactionKey jwk.Key) (string, error) {
+	from, err := transactionAddress(transactionKey)
+	if err != nil {
+		return "", err
+	}
+	return c.sendRegistryTransaction(accessToken, "revokeVerificationMethod", revokeVerificationMethodParams{
+		From:      from,
+		Did:       did,
+		VMethodId: VerificationMethodId(vMethodId),
+		NotAfter:  notAfter.Unix(),
+	}, transactionKey)
+}
+
+// InsertDidDocument registers the did document and its metadata in the did registry, the transaction key
+// becomes the controller of the did. It returns the transaction hash.
+func (c *Client) InsertDidDocument(accessToken string, did string, document map[string]interface{}, metadata map[string]interface{}, transactionKey jwk.Key) (string, error) {
+	params, err := newDocumentParams(did, document, metadata, map[string]string{"created": time.Now().UTC().Format(time.RFC3339)}, transactionKey)
+	if err != nil {
+		return "", err
+	}
+	return c.sendRegistryTransaction(accessToken, "insertDidDocument", params, transactionKey)
+}
+
+// UpdateDidDocument updates the did document and its metadata in the did registry, f.e. the metadata
+// {"deactivated": true} deactivates the did. It returns the transaction hash.
+func (c *Client) UpdateDidDocument(accessToken string, did string, document map[string]interface{}, metadata map[string]interface{}, transactionKey jwk.Key) (string, error) {
+	params, err := newDocumentParams(did, document, metadata, map[string]string{"updated": time.Now().UTC().Format(time.RFC3339)}, transactionKey)
+	if err != nil {
+		return "", err
+	}
+	return c.sendRegistryTransaction(accessToken, "updateDidDocument", params, transactionKey)
+}
+
+// newDocumentParams returns the hex encoded parameters of the did document, the hash is the sha256 of the
+// canonicalized document
+func newDocumentParams(did string, document map[string]interface{}, metadata map[string]interface{}, timestamp map[string]string, transactionKey jwk.Key) (documentParams, error) {
+	from, err := transactionAddress(transactionKey)
+	if err != nil {
+		return documentParams{}, err
+	}
+	canonicalizedDocument, err := jcs.Marshal(document)
+	if err != nil {
+		return documentParams{}, err
+	}
+	hashValue := sha256.Sum256(canonicalizedDocument)
+	documentJson, err := json.Marshal(document)
+	if err != nil {
+		return documentParams{}, err
+	}
+	metadataJson, err := json.Marshal(metadata)
+	if err != nil {
+		return documentParams{}, err
+	}
+	timestampJson, _ := json.Marshal(timestamp)
+	return documentParams{
+		From:               from,
+		Identifier:         hexutil.Encode([]byte(did)),
+		HashAlgorithmId:    0,
+		HashValue:          hexutil.Encode(hashValue[:]),
+		DidVersionInfo:     hexutil.Encode(documentJson),
+		TimestampData:      hexutil.Encode(timestampJson),
+		DidVersionMetadata: hexutil.Encode(metadataJson),
+	}, nil
+}
+
+// VerificationMethodId returns the fragment of the key id, the did registry identifies a method by the fragment
+func VerificationMethodId(keyId string) string {
+	if _, fragment, found := strings.Cut(keyId, "#"); found {
+		return fragment
+	}
+	return keyId
+}
+
+// sendRegistryTransaction requests the unsigned transaction of the method, signs it with the transaction
+// key and sends the signed transaction
+func (c *Client) sendRegistryTransaction(accessToken string, method string, params interface{}, transactionKey jwk.Key) (string, error) {
+	var (
+		unsignedTxn     unsignedTransaction
+		transactionHash string
+	)
+	if strings.TrimSpace(accessToken) == "" {
+		return "", errors.New("missing_access_token")
+	}
+	if transactionKey == nil {
+		return "", errors.New("missing_transaction_key")
+	}
+	if err := c.rpcCall(registryPath, accessToken, method, params, &unsignedTxn); err != nil {
+		return "", err
+	}
+	signedParams, err := signTransaction(unsignedTxn, transactionKey)
+	if err != nil {
+		return "", err
+	}
+	if err = c.rpcCall(registryPath, accessToken, "sendSignedTransaction", signedParams, &transactionHash); err != nil {
+		return "", err
+	}
+	return transactionHash, nil
+}
+
+// rpcCall posts the json-rpc request of the method and decodes the result
+func (c *Client) rpcCall(path string, accessToken string, method string, params interface{}, result interface{}) error {
+	response := rpcResponse{}
+	request := rpcRequest{Jsonrpc: "2.0", Method: method, Params: []interface{}{params}, Id: 1}
+	if err := c.httpPost(path, accessToken, request, &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// signTransaction signs the unsigned transaction with the transaction key (eip-155)
+func signTransaction(unsignedTxn unsignedTransaction, transactionKey jwk.Key) (signedTransactionParams, error) {
+	data, err := hexutil.Decode(unsignedTxn.Data)
+	if err != nil {
+		return signedTransactionParams{}, err
+	}
+	privateKey, err := secp256k1.NewPrivateKeyFromJwk(transactionKey)
+	if err != nil {
+		return signedTransactionParams{}, err
+	}
+	txn := types.NewTransaction(
+		hexToBigInt(unsignedTxn.Nonce).Uint64(),
+		common.HexToAddress(unsignedTxn.To),
+		hexToBigInt(unsignedTxn.Value),
+		hexToBigInt(unsignedTxn.GasLimit).Uint64(),
+		hexToBigInt(unsignedTxn.GasPrice),
+		data,
+	)
+	signedTxn, err := types.SignTx(txn, types.NewEIP155Signer(hexToBigInt(unsignedTxn.ChainId)), privateKey.PrivateKey)
+	if err != nil {
+		return signedTransactionParams{}, err
+	}
+	rawTxn, err := signedTxn.MarshalBinary()
+	if err != nil {
+		return signedTransactionParams{}, err
+	}
+	v, r, s := signedTxn.RawSignatureValues()
+	return signedTransactionParams{
+		Protocol:             "eth",
+		UnsignedTransaction:  unsignedTxn,
+		SignatureR:           hexutil.EncodeBig(r),
+		SignatureS:           hexutil.EncodeBig(s),
+		SignatureV:           hexutil.EncodeBig(v),
+		SignedRawTransaction: hexutil.Encode(rawTxn),
+	}, nil
+}
+
+// transactionAddress returns the ethereum address of the transaction key
+func transactionAddress(transactionKey jwk.Key) (string, error) {
+	if transactionKey == nil {
+		return "", errors.New("missing_transaction_key")
+	}
+	privateKey, err := secp256k1.NewPrivateKeyFromJwk(transactionKey)
+	if err != nil {
+		return "", err
+	}
+	return ethcrypto.PubkeyToAddress(privateKey.PublicKey).Hex(), nil
+}
+
+// encodePublicKey encodes the public key for the did registry, a secp256k1 key as uncompressed hex
+// and other keys as hex of the json web key
+func encodePublicKey(publicKey jwk.Key) (string, bool, error) {
+	if crv, ok := publicKey.Get("crv"); ok && fmt.Sprint(crv) == "secp256k1" {
+		rawKey := ecdsa.PublicKey{}
+		if err := publicKey.Raw(&rawKey); err != nil {
+			return "", false, err
+		}
+		return hexutil.Encode(ethcrypto.FromECDSAPub(&rawKey)), true, nil
+	}
+	jwkBytes, err := json.Marshal(publicKey)
+	if err != nil {
+		return "", false, err
+	}
+	return hexutil.Encode(jwkBytes), false, nil
+}
+
+// hexToBigInt decodes the hex string with or without the 0x prefix
+func hexToBigInt(hexString string) *big.Int {
+	value, _ := new(big.Int).SetString(strings.TrimPrefix(hexString, "0x"), 16)
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
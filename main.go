@@ -5,9 +5,9 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/gossif/admin/commands"
-	"github.com/spf13/cobra"
 )
 
 const (
@@ -35,36 +35,18 @@ const (
    %s `
 )
 
-var rootCmd = &cobra.Command{
-	Use:     "essif",
-	Version: Version,
-	Args:    cobra.ExactArgs(1),
-	Short:   "essif - a CLI to manage decentralized identifiers",
-}
+var rootCmd = commands.NewRootCmd()
 
 func init() {
+	rootCmd.Version = Version
 	rootCmd.Long = fmt.Sprintf(Banner, string(colorRed)+Version+string(colorReset), string(colorCyan)+"Implemented by Hietkamp IT-Consultancy"+string(colorReset))
-
-	rootCmd.AddCommand(commands.CreateCmd)
-	rootCmd.AddCommand(commands.RegisterCmd)
-	rootCmd.AddCommand(commands.OnboardCmd)
-	//rootCmd.AddCommand(commands.AccessTokenCmd)
-	rootCmd.AddCommand(commands.ResolveCmd)
-	rootCmd.AddCommand(commands.ListCmd)
-
-	commands.CreateCmd.Flags().StringP("method", "m", "", "the method used to create the did.")
-	commands.CreateCmd.Flags().StringP("domain", "d", "", "the domain for the web method.")
-	commands.OnboardCmd.Flags().StringP("did", "d", "", "the did to be onboarded.")
-	commands.RegisterCmd.Flags().StringP("did", "d", "", "the did to be registered.")
-	//commands.AccessTokenCmd.Flags().StringP("did", "d", "", "the did of the access token")
-	commands.ResolveCmd.Flags().StringP("did", "d", "", "the did of the document to resolve")
 }
 
-// Execute executes the root command.
+// Execute executes the root command and exits with the exit code of the error.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Printf("There was an error while executing your CLI\n'%s'\n", err)
-		return
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(commands.ExitCode(err))
 	}
 }
 
@@ -0,0 +1,189 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gossif/ebsi/secp256k1"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// handleAuthenticationRequest starts the did siop authentication of the authorisation api
+func (s *Server) handleAuthenticationRequest(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	request := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params := url.Values{}
+	params.Set("response_type", "id_token")
+	params.Set("client_id", baseUrl(r)+"/authorisation/v2/siop-sessions")
+	params.Set("scope", request["scope"])
+	params.Set("nonce", s.newNonce())
+	writeJson(w, http.StatusOK, map[string]string{"uri": "openid://?" + params.Encode()})
+}
+
+// handleSiopSession verifies the id token and the presentation of the verifiable authorisation, the nonce of the id
+// token must be issued by an authentication request. The access token is encrypted for the encryption key of the id
+// token and signed with the nonce of the id token (ake1).
+func (s *Server) handleSiopSession(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	request := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	idToken, subJwk, err := verifySelfIssued(request["id_token"])
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	nonceClaim, _ := idToken.Get("nonce")
+	nonce := fmt.Sprint(nonceClaim)
+	if !s.useNonce(nonce) {
+		writeProblem(w, http.StatusBadRequest, "invalid nonce")
+		return
+	}
+	didClaim, _ := idToken.Get("did")
+	did, _ := didClaim.(string)
+	if strings.TrimSpace(did) == "" {
+		writeProblem(w, http.StatusBadRequest, "missing did")
+		return
+	}
+	if err = s.verifyPresentation(request["vp_token"], did, subJwk); err != nil {
+		writeProblem(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	encryptionKey, err := encryptionKeyClaim(idToken)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	accessToken, err := jwt.NewBuilder().
+		Issuer(s.issuer).
+		Subject(did).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(s.hasAccessTokenTtl)).
+		Build()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	signedAccessToken, err := s.sign(accessToken)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plaintext, _ := json.Marshal(map[string]string{"access_token": signedAccessToken, "nonce": nonce, "did": did})
+	ephemeralKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ciphertext, err := ephemeralKey.Encrypt(encryptionKey, plaintext)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	encryptedPayload := hex.EncodeToString(ciphertext)
+	sigPayload, _ := json.Marshal(map[string]interface{}{
+		"iat":              time.Now().Unix(),
+		"exp":              accessToken.Expiration().Unix(),
+		"ake1_nonce":       nonce,
+		"ake1_enc_payload": encryptedPayload,
+		"did":              did,
+		"iss":              s.issuer,
+	})
+	jwsDetached, err := jws.Sign(nil, jws.WithKey(jwa.ES256, s.signingKey), jws.WithDetachedPayload(sigPayload))
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"ake1_enc_payload":  encryptedPayload,
+		"ake1_sig_payload":  json.RawMessage(sigPayload),
+		"ake1_jws_detached": string(jwsDetached),
+		"did":               did,
+	})
+}
+
+// verifyPresentation verifies the presentation of the did holds a verifiable authorisation issued to the key
+func (s *Server) verifyPresentation(vpToken string, did string, holderKey jwk.Key) error {
+	presentation, err := jwt.Parse([]byte(vpToken), jwt.WithKey(jwa.ES256K, holderKey), jwt.WithIssuer(did))
+	if err != nil {
+		return err
+	}
+	vp, _ := presentation.Get("vp")
+	vpClaims, _ := vp.(map[string]interface{})
+	credentials, _ := vpClaims["verifiableCredential"].([]interface{})
+	if len(credentials) == 0 {
+		return errors.New("missing verifiable authorisation")
+	}
+	authorisation, err := s.verify(fmt.Sprint(credentials[0]))
+	if err != nil {
+		return fmt.Errorf("invalid verifiable authorisation: %w", err)
+	}
+	thumbprint, err := jwkThumbprint(holderKey)
+	if err != nil {
+		return err
+	}
+	if authorisation.Subject() != thumbprint {
+		return errors.New("verifiable authorisation is not issued to the key")
+	}
+	return nil
+}
+
+// encryptionKeyClaim returns the public encryption key of the claims of the id token
+func encryptionKeyClaim(idToken jwt.Token) (*ecdsa.PublicKey, error) {
+	var (
+		publicKey ecdsa.PublicKey
+	)
+	claims, _ := idToken.Get("claims")
+	claimsMap, _ := claims.(map[string]interface{})
+	if claimsMap["encryption_key"] == nil {
+		return nil, errors.New("missing encryption_key")
+	}
+	keyBytes, err := json.Marshal(claimsMap["encryption_key"])
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwk.ParseKey(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err = key.Raw(&publicKey); err != nil {
+		return nil, err
+	}
+	return &publicKey, nil
+}
+
+// accessTokenDid verifies the access token of the request and returns the did of the token
+func (s *Server) accessTokenDid(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", errors.New("missing access token")
+	}
+	accessToken, err := s.verify(token)
+	if err != nil {
+		return "", err
+	}
+	return accessToken.Subject(), nil
+}
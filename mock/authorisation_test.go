@@ -0,0 +1,72 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build jwx_es256k
+
+package mock_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/mock"
+	"github.com/gossif/ebsi/secp256k1"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiopSession(t *testing.T) {
+	server, err := mock.NewServer()
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	signingKey, err := secp256k1.GeneratePrivateKeyAsJwk(testDid)
+	require.NoError(t, err)
+	publicKey, err := signingKey.PublicKey()
+	require.NoError(t, err)
+	postSession := func(nonce string) *http.Response {
+		idToken, err := jwt.NewBuilder().
+			Issuer("https://self-issued.me/v2").
+			IssuedAt(time.Now()).
+			Expiration(time.Now().Add(time.Minute)).
+			Claim("did", testDid).
+			Claim("nonce", nonce).
+			Claim("sub_jwk", publicKey).
+			Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(idToken, jwt.WithKey(jwa.ES256K, signingKey))
+		require.NoError(t, err)
+		body, _ := json.Marshal(map[string]string{"id_token": string(signed), "vp_token": "invalid"})
+		resp, err := http.Post(httpServer.URL+"/authorisation/v2/siop-sessions", "application/json", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("unknown nonce", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, postSession("unknown-nonce").StatusCode)
+	})
+	t.Run("issued nonce", func(t *testing.T) {
+		resp, err := http.Post(httpServer.URL+"/authorisation/v2/authentication-requests", "application/json", strings.NewReader(`{"scope":"openid did_authn"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		request := map[string]string{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&request))
+		_, rawQuery, _ := strings.Cut(request["uri"], "?")
+		params, err := url.ParseQuery(rawQuery)
+		require.NoError(t, err)
+
+		// the nonce is accepted once, the presentation is checked next
+		assert.Equal(t, http.StatusUnauthorized, postSession(params.Get("nonce")).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, postSession(params.Get("nonce")).StatusCode)
+	})
+}
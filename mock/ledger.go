@@ -0,0 +1,44 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// handleLedgerRpc handles the json-rpc requests of the ledger, only the transaction receipts are supported
+func (s *Server) handleLedgerRpc(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	serveRpc(w, r, "", map[string]rpcMethod{
+		"eth_blockNumber":           s.blockNumber,
+		"eth_getTransactionReceipt": s.transactionReceipt,
+	})
+}
+
+// blockNumber returns the number of the last block, every applied transaction has its own block
+func (s *Server) blockNumber(_ string, _ []json.RawMessage) (interface{}, *rpcError) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return hexutil.EncodeUint64(uint64(len(s.receipts))), nil
+}
+
+// transactionReceipt returns the receipt of the transaction hash, the result is null when unknown
+func (s *Server) transactionReceipt(_ string, params []json.RawMessage) (interface{}, *rpcError) {
+	var transactionHash string
+	if rpcErr := decodeParam(params, &transactionHash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	receipt, found := s.receipts[transactionHash]
+	if !found {
+		return nil, nil
+	}
+	return receipt, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// handleOnboardingRequest starts the onboarding of a user with the token of the eu login or captcha
+func (s *Server) handleOnboardingRequest(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	token := bearerToken(r)
+	if token == "" || (s.hasOnboardingToken != "" && token != s.hasOnboardingToken) {
+		writeProblem(w, http.StatusUnauthorized, "invalid onboarding token")
+		return
+	}
+	params := fmt.Sprintf("response_type=id_token&client_id=%s&scope=%s&nonce=%s",
+		url.QueryEscape(baseUrl(r)+"/users-onboarding/v2/authentication-responses"),
+		url.QueryEscape("openid did_authn"),
+		s.newNonce(),
+	)
+	writeJson(w, http.StatusOK, map[string]string{"session_token": "openid://?" + params})
+}
+
+// handleOnboardingResponse verifies the id token of the user and issues the verifiable authorisation
+func (s *Server) handleOnboardingResponse(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	request := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	idToken, subJwk, err := verifySelfIssued(request["id_token"])
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if nonce, _ := idToken.Get("nonce"); !s.useNonce(fmt.Sprint(nonce)) {
+		writeProblem(w, http.StatusBadRequest, "invalid nonce")
+		return
+	}
+	thumbprint, err := jwkThumbprint(subJwk)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	authorisation, err := jwt.NewBuilder().
+		Issuer(s.issuer).
+		Subject(thumbprint).
+		JwtID(fmt.Sprintf("urn:uuid:%s", uuid.NewString())).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(time.Hour*24*180)).
+		Claim("vc", map[string]interface{}{
+			"@context":          []string{"https://www.w3.org/2018/credentials/v1"},
+			"type":              []string{"VerifiableCredential", "VerifiableAuthorisation"},
+			"issuer":            s.issuer,
+			"credentialSubject": map[string]string{"id": thumbprint},
+		}).
+		Build()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	verifiableAuthorisation, err := s.sign(authorisation)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"verifiableCredential": verifiableAuthorisation})
+}
+
+// verifySelfIssued verifies the self-issued token with the key of the sub_jwk claim
+func verifySelfIssued(token string) (jwt.Token, jwk.Key, error) {
+	insecureToken, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	subJwk, found := insecureToken.Get("sub_jwk")
+	if !found {
+		return nil, nil, errors.New("missing sub_jwk")
+	}
+	subJwkBytes, err := json.Marshal(subJwk)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := jwk.ParseKey(subJwkBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	verifiedToken, err := jwt.Parse([]byte(token), jwt.WithKey(jwa.ES256K, publicKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	return verifiedToken, publicKey, nil
+}
+
+// jwkThumbprint returns the base64url encoded sha-256 thumbprint of the key
+func jwkThumbprint(key jwk.Key) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
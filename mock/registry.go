@@ -0,0 +1,431 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	registryAddress string = "0x5A3E1a2a8Bb2b0e4A2a8E4C3dA8d8aB6f1e2C3D4"
+	chainId         int64  = 6175
+)
+
+// verificationRelationships are the relationships of the verification methods of a did document
+var verificationRelationships = []string{"authentication", "assertionMethod", "keyAgreement", "capabilityInvocation", "capabilityDelegation"}
+
+// pendingTransaction is an unsigned transaction, the change is applied when the signed transaction is sent
+type pendingTransaction struct {
+	From  string
+	Apply func()
+}
+
+// transactionReceipt is the receipt of a transaction applied to the ledger
+type transactionReceipt struct {
+	TransactionHash string `json:"transactionHash"`
+	BlockNumber     string `json:"blockNumber"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Status          string `json:"status"`
+}
+
+type unsignedTransaction struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Data     string `json:"data"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	ChainId  string `json:"chainId"`
+	GasLimit string `json:"gasLimit"`
+	GasPrice string `json:"gasPrice"`
+}
+
+type insertDocumentParams struct {
+	From               string `json:"from"`
+	Identifier         string `json:"identifier"`
+	HashAlgorithmId    int    `json:"hashAlgorithmId"`
+	HashValue          string `json:"hashValue"`
+	DidVersionInfo     string `json:"didVersionInfo"`
+	TimestampData      string `json:"timestampData"`
+	DidVersionMetadata string `json:"didVersionMetadata"`
+}
+
+type addVerificationMethodParams struct {
+	From        string `json:"from"`
+	Did         string `json:"did"`
+	VMethodId   string `json:"vMethodId"`
+	PublicKey   string `json:"publicKey"`
+	IsSecp256k1 bool   `json:"isSecp256k1"`
+}
+
+type addVerificationRelationshipParams struct {
+	From         string `json:"from"`
+	Did          string `json:"did"`
+	Relationship string `json:"relationship"`
+	VMethodId    string `json:"vMethodId"`
+	NotBefore    int64  `json:"notBefore"`
+	NotAfter     int64  `json:"notAfter"`
+}
+
+type revokeVerificationMethodParams struct {
+	From      string `json:"from"`
+	Did       string `json:"did"`
+	VMethodId string `json:"vMethodId"`
+	NotAfter  int64  `json:"notAfter"`
+}
+
+type signedTransactionParams struct {
+	Protocol             string              `json:"protocol"`
+	UnsignedTransaction  unsignedTransaction `json:"unsignedTransaction"`
+	SignedRawTransaction string              `json:"signedRawTransaction"`
+}
+
+// handleResolve resolves the registered did document
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	did := strings.TrimPrefix(r.URL.Path, "/did-registry/v3/identifiers/")
+	document, found := s.Document(did)
+	if !found {
+		writeProblem(w, http.StatusNotFound, "identifier "+did+" not found")
+		return
+	}
+	writeJson(w, http.StatusOK, document)
+}
+
+// handleRegistryRpc handles the json-rpc requests of the did registry, the requests need an access token
+func (s *Server) handleRegistryRpc(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	did, err := s.accessTokenDid(r)
+	if err != nil {
+		writeProblem(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	serveRpc(w, r, did, map[string]rpcMethod{
+		"insertDidDocument":           s.insertDidDocument,
+		"addVerificationMethod":       s.addVerificationMethod,
+		"addVerificationRelationship": s.addVerificationRelationship,
+		"revokeVerificationMethod":    s.revokeVerificationMethod,
+		"updateDidDocument":           s.updateDidDocument,
+		"sendSignedTransaction":       s.sendSignedTransaction,
+	})
+}
+
+// insertDidDocument returns the unsigned transaction to register the did document of the did
+func (s *Server) insertDidDocument(did string, params []json.RawMessage) (interface{}, *rpcError) {
+	insertParams := insertDocumentParams{}
+	if rpcErr := decodeParam(params, &insertParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	identifier, err := hexutil.Decode(insertParams.Identifier)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid identifier: " + err.Error()}
+	}
+	if string(identifier) != did {
+		return nil, &rpcError{Code: rpcServerError, Message: "the access token is not issued to " + string(identifier)}
+	}
+	if _, found := s.Document(did); found {
+		return nil, &rpcError{Code: rpcServerError, Message: "identifier " + did + " already exists"}
+	}
+	document, metadata := map[string]interface{}{}, map[string]interface{}{}
+	if rpcErr := decodeHexJson(insertParams.DidVersionInfo, &document); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := decodeHexJson(insertParams.DidVersionMetadata, &metadata); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return s.newUnsignedTransaction(insertParams.From, func() {
+		s.documents[did] = document
+		s.metadata[did] = metadata
+		s.controllers[did] = insertParams.From
+	}), nil
+}
+
+// updateDidDocument returns the unsigned transaction to update the did document and its metadata
+func (s *Server) updateDidDocument(did string, params []json.RawMessage) (interface{}, *rpcError) {
+	updateParams := insertDocumentParams{}
+	if rpcErr := decodeParam(params, &updateParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	identifier, err := hexutil.Decode(updateParams.Identifier)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid identifier: " + err.Error()}
+	}
+	if rpcErr := s.verifyController(did, string(identifier), updateParams.From); rpcErr != nil {
+		return nil, rpcErr
+	}
+	document, metadata := map[string]interface{}{}, map[string]interface{}{}
+	if rpcErr := decodeHexJson(updateParams.DidVersionInfo, &document); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := decodeHexJson(updateParams.DidVersionMetadata, &metadata); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if document["id"] != did {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "the id of the did document is not " + did}
+	}
+	return s.newUnsignedTransaction(updateParams.From, func() {
+		s.documents[did] = document
+		s.metadata[did] = metadata
+	}), nil
+}
+
+// addVerificationMethod returns the unsigned transaction to add a verification method to the did document
+func (s *Server) addVerificationMethod(did string, params []json.RawMessage) (interface{}, *rpcError) {
+	methodParams := addVerificationMethodParams{}
+	if rpcErr := decodeParam(params, &methodParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := s.verifyController(did, methodParams.Did, methodParams.From); rpcErr != nil {
+		return nil, rpcErr
+	}
+	publicKey, rpcErr := decodePublicKey(methodParams.PublicKey, methodParams.IsSecp256k1)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	methodId := did + "#" + methodParams.VMethodId
+	document, _ := s.Document(did)
+	if findVerificationMethod(document, methodId) >= 0 {
+		return nil, &rpcError{Code: rpcServerError, Message: "verification method " + methodId + " already exists"}
+	}
+	return s.newUnsignedTransaction(methodParams.From, func() {
+		document := copyDocument(s.documents[did])
+		document["verificationMethod"] = append(listOf(document["verificationMethod"]), map[string]interface{}{
+			"id":           methodId,
+			"type":         "JsonWebKey2020",
+			"controller":   did,
+			"publicKeyJwk": publicKey,
+		})
+		s.documents[did] = document
+	}), nil
+}
+
+// addVerificationRelationship returns the unsigned transaction to add a verification method to a relationship
+func (s *Server) addVerificationRelationship(did string, params []json.RawMessage) (interface{}, *rpcError) {
+	relationshipParams := addVerificationRelationshipParams{}
+	if rpcErr := decodeParam(params, &relationshipParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := s.verifyController(did, relationshipParams.Did, relationshipParams.From); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if !isVerificationRelationship(relationshipParams.Relationship) {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid relationship: " + relationshipParams.Relationship}
+	}
+	if relationshipParams.NotAfter != 0 && relationshipParams.NotAfter < relationshipParams.NotBefore {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "notAfter is before notBefore"}
+	}
+	methodId := did + "#" + relationshipParams.VMethodId
+	document, _ := s.Document(did)
+	if findVerificationMethod(document, methodId) < 0 {
+		return nil, &rpcError{Code: rpcServerError, Message: "verification method " + methodId + " not found"}
+	}
+	return s.newUnsignedTransaction(relationshipParams.From, func() {
+		document := copyDocument(s.documents[did])
+		document[relationshipParams.Relationship] = append(listOf(document[relationshipParams.Relationship]), methodId)
+		s.documents[did] = document
+	}), nil
+}
+
+// revokeVerificationMethod returns the unsigned transaction to revoke a verification method, the revoked method
+// is removed from the did document and its relationships
+func (s *Server) revokeVerificationMethod(did string, params []json.RawMessage) (interface{}, *rpcError) {
+	revokeParams := revokeVerificationMethodParams{}
+	if rpcErr := decodeParam(params, &revokeParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if rpcErr := s.verifyController(did, revokeParams.Did, revokeParams.From); rpcErr != nil {
+		return nil, rpcErr
+	}
+	methodId := did + "#" + revokeParams.VMethodId
+	document, _ := s.Document(did)
+	if findVerificationMethod(document, methodId) < 0 {
+		return nil, &rpcError{Code: rpcServerError, Message: "verification method " + methodId + " not found"}
+	}
+	return s.newUnsignedTransaction(revokeParams.From, func() {
+		document := copyDocument(s.documents[did])
+		methods := listOf(document["verificationMethod"])
+		if i := findVerificationMethod(document, methodId); i >= 0 {
+			document["verificationMethod"] = append(methods[:i], methods[i+1:]...)
+		}
+		for _, relationship := range verificationRelationships {
+			if _, found := document[relationship]; !found {
+				continue
+			}
+			methodIds := []interface{}{}
+			for _, id := range listOf(document[relationship]) {
+				if id != methodId {
+					methodIds = append(methodIds, id)
+				}
+			}
+			document[relationship] = methodIds
+		}
+		s.documents[did] = document
+	}), nil
+}
+
+// verifyController returns an error when the did of the params is not the did of the access token, the
+// sender is not the controller of the registered did document or the did is deactivated
+func (s *Server) verifyController(did string, paramsDid string, from string) *rpcError {
+	if paramsDid != did {
+		return &rpcError{Code: rpcServerError, Message: "the access token is not issued to " + paramsDid}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, found := s.documents[did]; !found {
+		return &rpcError{Code: rpcServerError, Message: "identifier " + did + " not found"}
+	}
+	if controller, found := s.controllers[did]; found && !strings.EqualFold(controller, from) {
+		return &rpcError{Code: rpcServerError, Message: from + " is not a controller of " + did}
+	}
+	if deactivated, _ := s.metadata[did]["deactivated"].(bool); deactivated {
+		return &rpcError{Code: rpcServerError, Message: "identifier " + did + " is deactivated"}
+	}
+	return nil
+}
+
+// sendSignedTransaction verifies the signature of the transaction and applies the pending change
+func (s *Server) sendSignedTransaction(_ string, params []json.RawMessage) (interface{}, *rpcError) {
+	signedParams := signedTransactionParams{}
+	if rpcErr := decodeParam(params, &signedParams); rpcErr != nil {
+		return nil, rpcErr
+	}
+	rawTransaction, err := hexutil.Decode(signedParams.SignedRawTransaction)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid signedRawTransaction: " + err.Error()}
+	}
+	transaction := &types.Transaction{}
+	if err = transaction.UnmarshalBinary(rawTransaction); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid signedRawTransaction: " + err.Error()}
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(chainId)), transaction)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid signature: " + err.Error()}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	data := hexutil.Encode(transaction.Data())
+	pending, found := s.transactions[data]
+	if !found {
+		return nil, &rpcError{Code: rpcServerError, Message: "unknown transaction"}
+	}
+	if !strings.EqualFold(pending.From, sender.Hex()) {
+		return nil, &rpcError{Code: rpcServerError, Message: "the transaction is not signed by " + pending.From}
+	}
+	delete(s.transactions, data)
+	pending.Apply()
+	transactionHash := transaction.Hash().Hex()
+	s.receipts[transactionHash] = transactionReceipt{
+		TransactionHash: transactionHash,
+		BlockNumber:     hexutil.EncodeUint64(uint64(len(s.receipts) + 1)),
+		From:            sender.Hex(),
+		To:              registryAddress,
+		Status:          "0x1",
+	}
+	return transactionHash, nil
+}
+
+// newUnsignedTransaction returns an unsigned transaction for the sender, the data identifies the pending change
+func (s *Server) newUnsignedTransaction(from string, apply func()) unsignedTransaction {
+	dataBytes := make([]byte, 32)
+	rand.Read(dataBytes)
+	data := hexutil.Encode(dataBytes)
+	s.mutex.Lock()
+	s.transactions[data] = pendingTransaction{From: from, Apply: apply}
+	s.mutex.Unlock()
+	return unsignedTransaction{
+		From:     from,
+		To:       registryAddress,
+		Data:     data,
+		Value:    "0x0",
+		Nonce:    "0x0",
+		ChainId:  hexutil.EncodeBig(big.NewInt(chainId)),
+		GasLimit: "0x1000000",
+		GasPrice: "0x0",
+	}
+}
+
+// decodeHexJson decodes the json of the hex encoded param
+func decodeHexJson(hexValue string, value interface{}) *rpcError {
+	jsonBytes, err := hexutil.Decode(hexValue)
+	if err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	if err = json.Unmarshal(jsonBytes, value); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// decodePublicKey decodes the public key of a verification method as json web key, a secp256k1 key is
+// encoded as uncompressed hex and other keys as hex of the json web key
+func decodePublicKey(hexValue string, isSecp256k1 bool) (map[string]interface{}, *rpcError) {
+	if !isSecp256k1 {
+		publicKey := map[string]interface{}{}
+		if rpcErr := decodeHexJson(hexValue, &publicKey); rpcErr != nil {
+			return nil, rpcErr
+		}
+		return publicKey, nil
+	}
+	keyBytes, err := hexutil.Decode(hexValue)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid publicKey: " + err.Error()}
+	}
+	publicKey, err := ethcrypto.UnmarshalPubkey(keyBytes)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid publicKey: " + err.Error()}
+	}
+	return map[string]interface{}{
+		"kty": "EC",
+		"crv": "secp256k1",
+		"x":   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32))),
+	}, nil
+}
+
+// isVerificationRelationship returns true when the relationship is a verification relationship
+func isVerificationRelationship(relationship string) bool {
+	for _, verificationRelationship := range verificationRelationships {
+		if relationship == verificationRelationship {
+			return true
+		}
+	}
+	return false
+}
+
+// findVerificationMethod returns the index of the verification method in the did document, -1 when not found
+func findVerificationMethod(document map[string]interface{}, methodId string) int {
+	for i, method := range listOf(document["verificationMethod"]) {
+		if method, ok := method.(map[string]interface{}); ok && method["id"] == methodId {
+			return i
+		}
+	}
+	return -1
+}
+
+// listOf returns the list of the did document as a new slice
+func listOf(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return append([]interface{}{}, list...)
+}
+
+// copyDocument returns a copy of the did document, the resolved documents are never changed in place
+func copyDocument(document map[string]interface{}) map[string]interface{} {
+	documentCopy := map[string]interface{}{}
+	documentBytes, _ := json.Marshal(document)
+	json.Unmarshal(documentBytes, &documentCopy)
+	return documentCopy
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes of the json-rpc responses
+const (
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	Jsonrpc string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+	Id      interface{}       `json:"id"`
+}
+
+type rpcResponse struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Id      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcMethod handles the params of a json-rpc request of the did
+type rpcMethod func(did string, params []json.RawMessage) (interface{}, *rpcError)
+
+// serveRpc dispatches the json-rpc request to the method
+func serveRpc(w http.ResponseWriter, r *http.Request, did string, methods map[string]rpcMethod) {
+	request := rpcRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJson(w, http.StatusOK, rpcResponse{Jsonrpc: "2.0", Error: &rpcError{Code: rpcInvalidRequest, Message: err.Error()}})
+		return
+	}
+	method, found := methods[request.Method]
+	if !found {
+		writeJson(w, http.StatusOK, rpcResponse{Jsonrpc: "2.0", Id: request.Id, Error: &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + request.Method}})
+		return
+	}
+	result, rpcErr := method(did, request.Params)
+	writeJson(w, http.StatusOK, rpcResponse{Jsonrpc: "2.0", Id: request.Id, Result: result, Error: rpcErr})
+}
+
+// decodeParam decodes the first param of the request
+func decodeParam(params []json.RawMessage, value interface{}) *rpcError {
+	if len(params) == 0 {
+		return &rpcError{Code: rpcInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(params[0], value); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mock implements an in memory mock of the ebsi apis used by the cli, to run the commands
+// end-to-end without the ebsi infrastructure. The mock supports the users onboarding api, the
+// authorisation api, the did registry api and the ledger api.
+package mock
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gossif/admin/did"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// Server is the mock of the ebsi apis, it implements http.Handler
+type Server struct {
+	hasOnboardingToken string
+	hasAccessTokenTtl  time.Duration
+	mux                *http.ServeMux
+	mutex              sync.Mutex
+	issuer             string
+	signingKey         jwk.Key
+	nonces             map[string]bool
+	documents          map[string]map[string]interface{}
+	controllers        map[string]string
+	metadata           map[string]map[string]interface{}
+	transactions       map[string]pendingTransaction
+	receipts           map[string]transactionReceipt
+}
+
+type serverOption func(*Server)
+
+// WithOnboardingToken sets the option of the only onboarding token accepted, defaults to any token
+func WithOnboardingToken(token string) serverOption {
+	return func(s *Server) {
+		s.hasOnboardingToken = token
+	}
+}
+
+// WithAccessTokenTtl sets the option of the lifetime of the access tokens
+func WithAccessTokenTtl(ttl time.Duration) serverOption {
+	return func(s *Server) {
+		s.hasAccessTokenTtl = ttl
+	}
+}
+
+// WithDocument sets the option of a did document that is already registered
+func WithDocument(did string, document map[string]interface{}) serverOption {
+	return func(s *Server) {
+		s.documents[did] = document
+	}
+}
+
+// NewServer returns the mock, the credentials and access tokens are signed with a generated key
+func NewServer(options ...serverOption) (*Server, error) {
+	rawKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := jwk.FromRaw(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, _ := signingKey.PublicKey()
+	issuer, err := did.NewKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	signingKey.Set(jwk.KeyIDKey, did.KeyVerificationMethodId(issuer))
+	s := &Server{
+		hasAccessTokenTtl: time.Minute * 15,
+		mux:               http.NewServeMux(),
+		issuer:            issuer,
+		signingKey:        signingKey,
+		nonces:            map[string]bool{},
+		documents:         map[string]map[string]interface{}{},
+		controllers:       map[string]string{},
+		metadata:          map[string]map[string]interface{}{},
+		transactions:      map[string]pendingTransaction{},
+		receipts:          map[string]transactionReceipt{},
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	s.mux.HandleFunc("/users-onboarding/v2/authentication-requests", s.handleOnboardingRequest)
+	s.mux.HandleFunc("/users-onboarding/v2/authentication-responses", s.handleOnboardingResponse)
+	s.mux.HandleFunc("/authorisation/v2/authentication-requests", s.handleAuthenticationRequest)
+	s.mux.HandleFunc("/authorisation/v2/siop-sessions", s.handleSiopSession)
+	s.mux.HandleFunc("/did-registry/v3/identifiers/", s.handleResolve)
+	s.mux.HandleFunc("/did-registry/v3/jsonrpc", s.handleRegistryRpc)
+	s.mux.HandleFunc("/ledger/v3/blockchains/besu", s.handleLedgerRpc)
+	return s, nil
+}
+
+// Issuer returns the did of the issuer of the verifiable authorisations and access tokens
+func (s *Server) Issuer() string {
+	return s.issuer
+}
+
+// Document returns the registered did document of the did
+func (s *Server) Document(did string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	document, found := s.documents[did]
+	return document, found
+}
+
+// Metadata returns the metadata of the registered did document of the did, f.e. {"deactivated": true}
+func (s *Server) Metadata(did string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	metadata, found := s.metadata[did]
+	return metadata, found
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// newNonce returns a random nonce, the nonce is remembered until it is used
+func (s *Server) newNonce() string {
+	nonceBytes := make([]byte, 16)
+	rand.Read(nonceBytes)
+	nonce := hex.EncodeToString(nonceBytes)
+	s.mutex.Lock()
+	s.nonces[nonce] = true
+	s.mutex.Unlock()
+	return nonce
+}
+
+// useNonce returns true when the nonce was issued and not used before
+func (s *Server) useNonce(nonce string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.nonces[nonce] {
+		return false
+	}
+	delete(s.nonces, nonce)
+	return true
+}
+
+// sign signs the token with the key of the mock
+func (s *Server) sign(token jwt.Token) (string, error) {
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, s.signingKey))
+	return string(signed), err
+}
+
+// verify verifies a token signed by the mock
+func (s *Server) verify(token string) (jwt.Token, error) {
+	publicKey, err := s.signingKey.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Parse([]byte(token), jwt.WithKey(jwa.ES256, publicKey), jwt.WithIssuer(s.issuer))
+}
+
+// bearerToken returns the token of the authorization header
+func bearerToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+}
+
+// baseUrl returns the url of the mock as seen by the client
+func baseUrl(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+	return "http://" + r.Host
+}
+
+// writeJson writes the value as json response
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeProblem writes a problem details response like the ebsi apis
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"title":  http.StatusText(status),
+		"status": status,
+		"detail": detail,
+	})
+}
+
+// allowMethod writes a problem response when the http method is not allowed
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed")
+		return false
+	}
+	return true
+}
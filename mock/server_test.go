@@ -0,0 +1,68 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package mock_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gossif/admin/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDid string = "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"
+
+func TestServer(t *testing.T) {
+	server, err := mock.NewServer(
+		mock.WithOnboardingToken("onboarding-token"),
+		mock.WithDocument(testDid, map[string]interface{}{"id": testDid}),
+	)
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	t.Run("resolve", func(t *testing.T) {
+		resp, err := http.Get(httpServer.URL + "/did-registry/v3/identifiers/" + testDid)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		document := map[string]interface{}{}
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+		assert.Equal(t, testDid, document["id"])
+	})
+	t.Run("resolve unknown", func(t *testing.T) {
+		resp, err := http.Get(httpServer.URL + "/did-registry/v3/identifiers/did:ebsi:zvHWX359A3CvfJnCYaAiAde")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+	t.Run("onboarding token", func(t *testing.T) {
+		for token, status := range map[string]int{"onboarding-token": http.StatusOK, "invalid-token": http.StatusUnauthorized} {
+			req, _ := http.NewRequest(http.MethodPost, httpServer.URL+"/users-onboarding/v2/authentication-requests", strings.NewReader(`{"scope":"ebsi users onboarding"}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, status, resp.StatusCode)
+		}
+	})
+	t.Run("registry without access token", func(t *testing.T) {
+		resp, err := http.Post(httpServer.URL+"/did-registry/v3/jsonrpc", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"insertDidDocument","params":[],"id":1}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+	t.Run("ledger", func(t *testing.T) {
+		resp, err := http.Post(httpServer.URL+"/ledger/v3/blockchains/besu", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		response := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, "0x0", response["result"])
+	})
+}
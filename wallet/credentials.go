@@ -0,0 +1,173 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	// credentialPrefix is the reserved prefix of the keys of the credentials
+	credentialPrefix string = "essif:vc:"
+	// credentialIndexKey is the reserved key of the index of the credentials
+	credentialIndexKey string = "essif:vcindex"
+)
+
+// CredentialEntry is the entry of a credential in the index of the wallet
+type CredentialEntry struct {
+	Id             string     `json:"id"`
+	Format         string     `json:"format"`
+	Issuer         string     `json:"issuer"`
+	Subject        string     `json:"subject,omitempty"`
+	Types          []string   `json:"type"`
+	IssuanceDate   time.Time  `json:"issuanceDate"`
+	ExpirationDate *time.Time `json:"expirationDate,omitempty"`
+	StoredAt       time.Time  `json:"storedAt"`
+}
+
+// Expired returns true when the credential is expired at the time
+func (entry CredentialEntry) Expired(now time.Time) bool {
+	return entry.ExpirationDate != nil && now.After(*entry.ExpirationDate)
+}
+
+// HasType returns true when the credential is of the type
+func (entry CredentialEntry) HasType(credentialType string) bool {
+	for _, t := range entry.Types {
+		if t == credentialType {
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialFilter selects the credentials of the index, the empty fields select every credential
+type CredentialFilter struct {
+	Subject string
+	Issuer  string
+	Type    string
+	// Expired selects only the credentials that are expired
+	Expired bool
+}
+
+func (filter CredentialFilter) matches(entry CredentialEntry, now time.Time) bool {
+	return (filter.Subject == "" || entry.Subject == filter.Subject) &&
+		(filter.Issuer == "" || entry.Issuer == filter.Issuer) &&
+		(filter.Type == "" || entry.HasType(filter.Type)) &&
+		(!filter.Expired || entry.Expired(now))
+}
+
+// StoreCredential stores the credential with the entry in the index, a credential with the same id is replaced.
+// A credential without id gets the digest of the credential as id. It returns the stored entry.
+func StoreCredential(entry CredentialEntry, credential []byte) (CredentialEntry, error) {
+	if dbStore == nil {
+		return CredentialEntry{}, ErrNotOpen
+	}
+	if entry.Id == "" {
+		digest := sha256.Sum256(credential)
+		entry.Id = "urn:sha256:" + hex.EncodeToString(digest[:])
+	}
+	entry.StoredAt = time.Now().UTC()
+	index, err := credentialIndex()
+	if err != nil {
+		return CredentialEntry{}, err
+	}
+	if err = encryptValue(credentialPrefix+entry.Id, credential); err != nil {
+		return CredentialEntry{}, err
+	}
+	index[entry.Id] = entry
+	return entry, storeCredentialIndex(index)
+}
+
+// GetCredential returns the credential with the id and its entry in the index, or ErrNotFound
+func GetCredential(id string) (CredentialEntry, []byte, error) {
+	if dbStore == nil {
+		return CredentialEntry{}, nil, ErrNotOpen
+	}
+	index, err := credentialIndex()
+	if err != nil {
+		return CredentialEntry{}, nil, err
+	}
+	entry, found := index[id]
+	if !found {
+		return CredentialEntry{}, nil, ErrNotFound
+	}
+	credential, err := decryptValue(credentialPrefix + id)
+	if err != nil {
+		return CredentialEntry{}, nil, err
+	}
+	return entry, credential, nil
+}
+
+// RemoveCredential removes the credential with the id from the wallet
+func RemoveCredential(id string) error {
+	if dbStore == nil {
+		return ErrNotOpen
+	}
+	index, err := credentialIndex()
+	if err != nil {
+		return err
+	}
+	if _, found := index[id]; !found {
+		return ErrNotFound
+	}
+	if err = dbStore.Delete(credentialPrefix + id); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	delete(index, id)
+	return storeCredentialIndex(index)
+}
+
+// Credentials returns the entries of the credentials selected by the filter, ordered by issuance date
+func Credentials(filter CredentialFilter) ([]CredentialEntry, error) {
+	if dbStore == nil {
+		return nil, ErrNotOpen
+	}
+	index, err := credentialIndex()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entries := []CredentialEntry{}
+	for _, entry := range index {
+		if filter.matches(entry, now) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IssuanceDate.Equal(entries[j].IssuanceDate) {
+			return entries[i].Id < entries[j].Id
+		}
+		return entries[i].IssuanceDate.Before(entries[j].IssuanceDate)
+	})
+	return entries, nil
+}
+
+// credentialIndex returns the index of the credentials by id, the index is empty when no credential is stored
+func credentialIndex() (map[string]CredentialEntry, error) {
+	index := map[string]CredentialEntry{}
+	indexBytes, err := decryptValue(credentialIndexKey)
+	if errors.Is(err, ErrNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(indexBytes, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func storeCredentialIndex(index map[string]CredentialEntry) error {
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return encryptValue(credentialIndexKey, indexBytes)
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentials(t *testing.T) {
+	holder := "did:key:zDnaeS4AhtqpWWZdR9UMtX9ZL57tqa7MhAuPAfMNLN6ZC53Nf"
+	issuer := "did:ebsi:zrP7S6ZqEn1vWPDMdTyx5jW"
+	expired := time.Now().Add(-time.Hour)
+	attestation := wallet.CredentialEntry{Id: "urn:uuid:1", Format: "jwt", Issuer: issuer, Subject: holder,
+		Types: []string{"VerifiableCredential", "VerifiableAttestation"}, IssuanceDate: time.Now().Add(-2 * time.Hour)}
+	diploma := wallet.CredentialEntry{Format: "json-ld", Issuer: "did:web:example.org", Subject: holder,
+		Types: []string{"VerifiableCredential", "VerifiableDiploma"}, IssuanceDate: time.Now().Add(-3 * time.Hour), ExpirationDate: &expired}
+
+	t.Run("StoreCredential", func(t *testing.T) {
+		stored, err := wallet.StoreCredential(attestation, []byte("eyJhbGciOiJFUzI1NiJ9.e30.c2ln"))
+		require.NoError(t, err)
+		assert.Equal(t, "urn:uuid:1", stored.Id)
+		stored, err = wallet.StoreCredential(diploma, []byte(`{"type": ["VerifiableCredential", "VerifiableDiploma"]}`))
+		require.NoError(t, err)
+		assert.Contains(t, stored.Id, "urn:sha256:")
+		diploma.Id = stored.Id
+
+		dids, err := wallet.GetAllKeys()
+		require.NoError(t, err)
+		assert.NotContains(t, dids, "essif:vc:urn:uuid:1")
+	})
+	t.Run("GetCredential", func(t *testing.T) {
+		entry, credential, err := wallet.GetCredential("urn:uuid:1")
+		require.NoError(t, err)
+		assert.Equal(t, issuer, entry.Issuer)
+		assert.Equal(t, "eyJhbGciOiJFUzI1NiJ9.e30.c2ln", string(credential))
+		_, _, err = wallet.GetCredential("urn:uuid:2")
+		assert.ErrorIs(t, err, wallet.ErrNotFound)
+	})
+	t.Run("Credentials", func(t *testing.T) {
+		entries, err := wallet.Credentials(wallet.CredentialFilter{Subject: holder})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, diploma.Id, entries[0].Id)
+
+		entries, err = wallet.Credentials(wallet.CredentialFilter{Type: "VerifiableAttestation", Issuer: issuer})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "urn:uuid:1", entries[0].Id)
+
+		entries, err = wallet.Credentials(wallet.CredentialFilter{Expired: true})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, diploma.Id, entries[0].Id)
+	})
+	t.Run("RemoveCredential", func(t *testing.T) {
+		require.NoError(t, wallet.RemoveCredential(diploma.Id))
+		require.NoError(t, wallet.RemoveCredential("urn:uuid:1"))
+		assert.ErrorIs(t, wallet.RemoveCredential("urn:uuid:1"), wallet.ErrNotFound)
+		entries, err := wallet.Credentials(wallet.CredentialFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
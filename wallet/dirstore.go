@@ -0,0 +1,120 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const dirStoreExtension string = ".json"
+
+// DirStore stores every value as a json file in a directory
+type DirStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewDirStore create a store instance based on a directory, the directory is created when it doesn't exist
+func NewDirStore(dir string) (*DirStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("directory is empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// fileName returns the file of the key, the key is escaped to be safe on every platform
+func (d *DirStore) fileName(key string) string {
+	return filepath.Join(d.dir, url.QueryEscape(key)+dirStoreExtension)
+}
+
+func (d *DirStore) Get(key string) (string, error) {
+	if strings.TrimSpace(key) == "" {
+		return "", ErrNotFound
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	value, err := os.ReadFile(d.fileName(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Put writes the value to a temporary file first, to not leave a partial file behind
+func (d *DirStore) Put(key string, value string) error {
+	if strings.TrimSpace(key) == "" {
+		return errors.New("key is empty")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tmpFile, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.WriteString(value); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), d.fileName(key))
+}
+
+func (d *DirStore) Delete(key string) error {
+	if strings.TrimSpace(key) == "" {
+		return ErrNotFound
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	err := os.Remove(d.fileName(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (d *DirStore) List() ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	allKeys := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, dirStoreExtension) {
+			continue
+		}
+		key, err := url.QueryUnescape(strings.TrimSuffix(name, dirStoreExtension))
+		if err != nil {
+			continue
+		}
+		allKeys = append(allKeys, key)
+	}
+	sort.Strings(allKeys)
+	return allKeys, nil
+}
+
+func (d *DirStore) Close() error {
+	return nil
+}
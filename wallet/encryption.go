@@ -0,0 +1,248 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// headerKey is the reserved key holding the kdf parameters of the wallet
+	headerKey     string = "essif:wallet"
+	headerVersion int    = 1
+	kdfArgon2id   string = "argon2id"
+	checkValue    string = "essif wallet"
+)
+
+var (
+	ErrWalletLocked      = errors.New("wallet is locked")
+	ErrInvalidPassphrase = errors.New("invalid passphrase")
+)
+
+// sealKey is the key derived from the passphrase, nil when the wallet is locked
+var sealKey []byte
+
+// kdfParams are the parameters to derive the wallet key from the passphrase
+type kdfParams struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keyLen"`
+}
+
+// sealedValue is a value encrypted with aes-256-gcm
+type sealedValue struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ct"`
+}
+
+// walletHeader is stored unencrypted and is used to verify the passphrase
+type walletHeader struct {
+	Version int         `json:"version"`
+	Kdf     kdfParams   `json:"kdf"`
+	Check   sealedValue `json:"check"`
+}
+
+// defaultKdfParams returns the argon2id parameters as recommended by RFC 9106
+func defaultKdfParams() (kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return kdfParams{}, err
+	}
+	return kdfParams{
+		Name:    kdfArgon2id,
+		Salt:    salt,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		KeyLen:  32,
+	}, nil
+}
+
+// deriveKey derives the wallet key from the passphrase
+func (p kdfParams) deriveKey(passphrase string) ([]byte, error) {
+	switch p.Name {
+	case kdfArgon2id:
+		return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, p.KeyLen), nil
+	default:
+		return nil, errors.New("unsupported kdf")
+	}
+}
+
+// IsInitialized returns true when the wallet is protected with a passphrase
+func IsInitialized() (bool, error) {
+	if dbStore == nil {
+		return false, ErrNotOpen
+	}
+	_, err := dbStore.Get(headerKey)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Unlock derives the wallet key from the passphrase. An uninitialized wallet is protected
+// with the passphrase, buckets stored before that are encrypted on the fly.
+func Unlock(passphrase string) error {
+	if strings.TrimSpace(passphrase) == "" {
+		return errors.New("passphrase is empty")
+	}
+	if dbStore == nil {
+		return ErrNotOpen
+	}
+	headerString, err := dbStore.Get(headerKey)
+	if err == ErrNotFound {
+		return initialize(passphrase)
+	}
+	if err != nil {
+		return err
+	}
+	header := walletHeader{}
+	if err = json.Unmarshal([]byte(headerString), &header); err != nil {
+		return err
+	}
+	key, err := header.Kdf.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	check, err := open(key, headerKey, header.Check)
+	if err != nil || subtle.ConstantTimeCompare(check, []byte(checkValue)) != 1 {
+		return ErrInvalidPassphrase
+	}
+	sealKey = key
+	return nil
+}
+
+// Lock removes the wallet key from memory
+func Lock() {
+	for i := range sealKey {
+		sealKey[i] = 0
+	}
+	sealKey = nil
+}
+
+// initialize stores a new wallet header and encrypts the plaintext buckets
+func initialize(passphrase string) error {
+	params, err := defaultKdfParams()
+	if err != nil {
+		return err
+	}
+	key, err := params.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	check, err := seal(key, headerKey, []byte(checkValue))
+	if err != nil {
+		return err
+	}
+	keys, err := dbStore.List()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		value, err := dbStore.Get(k)
+		if err != nil {
+			return err
+		}
+		sealed, err := seal(key, k, []byte(value))
+		if err != nil {
+			return err
+		}
+		if err = storeSealed(k, sealed); err != nil {
+			return err
+		}
+	}
+	headerBytes, err := json.Marshal(walletHeader{Version: headerVersion, Kdf: params, Check: check})
+	if err != nil {
+		return err
+	}
+	if err = dbStore.Put(headerKey, string(headerBytes)); err != nil {
+		return err
+	}
+	sealKey = key
+	return nil
+}
+
+// seal encrypts the plaintext, the key of the value is bound as additional data
+func seal(key []byte, aad string, plaintext []byte) (sealedValue, error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return sealedValue{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return sealedValue{}, err
+	}
+	return sealedValue{Nonce: nonce, Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(aad))}, nil
+}
+
+// open decrypts the sealed value
+func open(key []byte, aad string, sealed sealedValue) ([]byte, error) {
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.New("value is not encrypted")
+	}
+	return aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(aad))
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// storeSealed persists the encrypted value with key
+func storeSealed(key string, sealed sealedValue) error {
+	sealedBytes, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return dbStore.Put(key, string(sealedBytes))
+}
+
+// encryptValue encrypts and persists the value with key
+func encryptValue(key string, value []byte) error {
+	if sealKey == nil {
+		return ErrWalletLocked
+	}
+	sealed, err := seal(sealKey, key, value)
+	if err != nil {
+		return err
+	}
+	return storeSealed(key, sealed)
+}
+
+// decryptValue reads and decrypts the value with key
+func decryptValue(key string) ([]byte, error) {
+	if sealKey == nil {
+		return nil, ErrWalletLocked
+	}
+	sealedString, err := dbStore.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := sealedValue{}
+	if err = json.Unmarshal([]byte(sealedString), &sealed); err != nil {
+		return nil, err
+	}
+	return open(sealKey, key, sealed)
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Run("InvalidPassphrase", func(t *testing.T) {
+		err := wallet.Unlock("incorrect passphrase")
+		assert.ErrorIs(t, err, wallet.ErrInvalidPassphrase)
+	})
+	t.Run("Locked", func(t *testing.T) {
+		var (
+			expectedDid string = "did:example:456"
+		)
+		expectedDidBucket := wallet.DidBucket{Did: expectedDid}
+		expectedDidBucket.IssuanceKey, _ = generateSecp256r1AsJwk(expectedDid)
+		assert.NoError(t, wallet.StoreBucket(expectedDidBucket))
+
+		wallet.Lock()
+		defer wallet.Unlock(testPassphrase)
+
+		_, err := wallet.GetBucketByDid(expectedDid)
+		assert.ErrorIs(t, err, wallet.ErrWalletLocked)
+		err = wallet.StoreBucket(expectedDidBucket)
+		assert.ErrorIs(t, err, wallet.ErrWalletLocked)
+	})
+	t.Run("Unlocked", func(t *testing.T) {
+		var (
+			expectedDid string = "did:example:456"
+		)
+		initialized, err := wallet.IsInitialized()
+		assert.NoError(t, err)
+		assert.True(t, initialized)
+
+		actualDidBucket, err := wallet.GetBucketByDid(expectedDid)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedDid, actualDidBucket.Did)
+		assert.NotNil(t, actualDidBucket.IssuanceKey)
+
+		identifiers, err := wallet.GetAllKeys()
+		assert.NoError(t, err)
+		assert.Contains(t, identifiers, expectedDid)
+		assert.NotContains(t, identifiers, "essif:wallet")
+	})
+}
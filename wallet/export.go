@@ -0,0 +1,256 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// ExportContentType is the content type of the payload of an exported bucket
+	ExportContentType string = "essif-bucket+json"
+	exportVersion     int    = 1
+	// exportKeyAlgorithm derives the key encryption key from the passphrase with PBKDF2
+	exportKeyAlgorithm jwa.KeyEncryptionAlgorithm = jwa.PBES2_HS512_A256KW
+	// exportCount is the number of PBKDF2 iterations of an export, as recommended by OWASP for HMAC-SHA512
+	exportCount int = 210000
+	// MaxExportCount is the maximum number of PBKDF2 iterations of an export that is decrypted
+	MaxExportCount int = 1000000
+	// MinExportPassphraseLength is the minimum length of the passphrase of an export
+	MinExportPassphraseLength int = 8
+)
+
+var (
+	ErrInvalidExport           = errors.New("invalid export")
+	ErrInvalidExportPassphrase = errors.New("invalid passphrase of the export")
+	ErrWeakPassphrase          = fmt.Errorf("the passphrase must have at least %d characters", MinExportPassphraseLength)
+)
+
+// ExportedBucket is the payload of an export, the digest is the sha256 of the bucket
+type ExportedBucket struct {
+	Version    int             `json:"version"`
+	Did        string          `json:"did"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Digest     string          `json:"digest"`
+	Bucket     json.RawMessage `json:"bucket"`
+}
+
+// ExportBucket encrypts the bucket of the did as a JWE in the JSON serialization. The content encryption key
+// is wrapped with a key derived from the passphrase, the passphrase is independent of the wallet passphrase.
+func ExportBucket(did string, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinExportPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	bucket, err := GetBucketByDid(did)
+	if err != nil {
+		return nil, err
+	}
+	bucketBytes, err := bucket.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(bucketBytes)
+	payload, err := json.Marshal(ExportedBucket{
+		Version:    exportVersion,
+		Did:        did,
+		ExportedAt: time.Now().UTC(),
+		Digest:     hex.EncodeToString(digest[:]),
+		Bucket:     bucketBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return encryptExport(payload, passphrase, exportCount)
+}
+
+// encryptExport encrypts the payload with PBES2-HS512+A256KW and A256GCM. The JWE is built here because
+// jwx always derives the key encryption key with 10000 iterations, the count must be set explicitly.
+func encryptExport(payload []byte, passphrase string, count int) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	headers := jwe.NewHeaders()
+	for key, value := range map[string]interface{}{
+		jwe.AlgorithmKey:         exportKeyAlgorithm,
+		jwe.ContentEncryptionKey: jwa.A256GCM,
+		jwe.ContentTypeKey:       ExportContentType,
+		jwe.SaltKey:              base64.RawURLEncoding.EncodeToString(salt),
+		jwe.CountKey:             count,
+	} {
+		if err := headers.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	aad, err := headers.Encode()
+	if err != nil {
+		return nil, err
+	}
+	// the salt input of PBES2 is the algorithm, a zero octet and the salt, RFC 7518 section 4.8.1.1
+	saltInput := append(append([]byte(exportKeyAlgorithm.String()), 0), salt...)
+	kek := pbkdf2.Key([]byte(passphrase), saltInput, count, 32, sha512.New)
+	cek := make([]byte, 32)
+	if _, err = rand.Read(cek); err != nil {
+		return nil, err
+	}
+	encryptedKey, err := wrapKey(kek, cek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAead(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nil, iv, payload, aad)
+	tagStart := len(sealed) - aead.Overhead()
+
+	// the algorithm is in the header of the recipient as well, jwx matches the key with it
+	recipient := jwe.NewRecipient()
+	if err = recipient.Headers().Set(jwe.AlgorithmKey, exportKeyAlgorithm); err != nil {
+		return nil, err
+	}
+	if err = recipient.SetEncryptedKey(encryptedKey); err != nil {
+		return nil, err
+	}
+	message := jwe.NewMessage()
+	for key, value := range map[string]interface{}{
+		jwe.ProtectedHeadersKey:     headers,
+		jwe.RecipientsKey:           []jwe.Recipient{recipient},
+		jwe.InitializationVectorKey: iv,
+		jwe.CipherTextKey:           sealed[:tagStart],
+		jwe.TagKey:                  sealed[tagStart:],
+	} {
+		if err = message.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(message)
+}
+
+// wrapKey wraps the content encryption key with the AES key wrap of RFC 3394
+func wrapKey(kek []byte, cek []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(cek) / 8
+	wrapped := make([]byte, 8*(n+1))
+	copy(wrapped[8:], cek)
+	a := []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+	b := make([]byte, aes.BlockSize)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, a)
+			copy(b[8:], wrapped[8*i:8*i+8])
+			block.Encrypt(b, b)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^uint64(n*j+i))
+			copy(wrapped[8*i:], b[8:])
+		}
+	}
+	copy(wrapped, a)
+	return wrapped, nil
+}
+
+// checkExportCount fails when the PBKDF2 iterations of the headers exceed MaxExportCount
+func checkExportCount(headers jwe.Headers) error {
+	if headers == nil {
+		return nil
+	}
+	value, ok := headers.Get(jwe.CountKey)
+	if !ok {
+		return nil
+	}
+	count, ok := value.(float64)
+	if !ok {
+		return fmt.Errorf("unexpected type %T of the count", value)
+	}
+	if count > float64(MaxExportCount) {
+		return fmt.Errorf("the count %.0f exceeds the maximum of %d", count, MaxExportCount)
+	}
+	return nil
+}
+
+// DecryptExport decrypts the export with the passphrase and checks the integrity of the bucket
+func DecryptExport(data []byte, passphrase string) (DidBucket, ExportedBucket, error) {
+	message, err := jwe.Parse(bytes.TrimSpace(data))
+	if err != nil {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if message.ProtectedHeaders().ContentType() != ExportContentType {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: unexpected content type %s", ErrInvalidExport, message.ProtectedHeaders().ContentType())
+	}
+	// the count is checked before decrypting, jwx derives the key with any count of the export
+	headers := []jwe.Headers{message.ProtectedHeaders(), message.UnprotectedHeaders()}
+	for _, recipient := range message.Recipients() {
+		headers = append(headers, recipient.Headers())
+	}
+	for _, h := range headers {
+		if err = checkExportCount(h); err != nil {
+			return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+	}
+	payload, err := jwe.Decrypt(bytes.TrimSpace(data), jwe.WithKey(exportKeyAlgorithm, []byte(passphrase)))
+	if err != nil {
+		return DidBucket{}, ExportedBucket{}, ErrInvalidExportPassphrase
+	}
+	exported := ExportedBucket{}
+	if err = json.Unmarshal(payload, &exported); err != nil {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if exported.Version != exportVersion {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, exported.Version)
+	}
+	digest := sha256.Sum256(exported.Bucket)
+	if hex.EncodeToString(digest[:]) != exported.Digest {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: the digest of the bucket doesn't match", ErrInvalidExport)
+	}
+	bucket := DidBucket{}
+	if err = json.Unmarshal(exported.Bucket, &bucket); err != nil {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	if bucket.Did == "" || bucket.Did != exported.Did {
+		return DidBucket{}, ExportedBucket{}, fmt.Errorf("%w: the did of the bucket doesn't match", ErrInvalidExport)
+	}
+	return bucket, exported, nil
+}
+
+// ImportBucket stores the imported bucket, a did in the wallet is refused with ErrBucketExists unless overwrite
+// is set. The overwritten bucket is moved to the trash, replaced is true when a bucket is overwritten.
+func ImportBucket(bucket DidBucket, overwrite bool) (replaced bool, err error) {
+	if dbStore == nil {
+		return false, ErrNotOpen
+	}
+	_, err = GetBucketByDid(bucket.Did)
+	switch {
+	case err == nil && !overwrite:
+		return false, ErrBucketExists
+	case err == nil:
+		if err = RemoveBucket(bucket.Did, false); err != nil {
+			return false, err
+		}
+		replaced = true
+	case !errors.Is(err, ErrNotFound):
+		return false, err
+	}
+	return replaced, StoreBucket(bucket)
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	did := "did:ebsi:zxaYaUtb8pvoAtYNWbKcveg"
+	bucket := wallet.DidBucket{Did: did, Token: "eyJ0eXAiOiJKV1QifQ"}
+	bucket.IssuanceKey, _ = generateSecp256r1AsJwk(did)
+	require.NoError(t, bucket.ChangeState(wallet.StateChange{State: wallet.StateCreated}))
+	require.NoError(t, wallet.StoreBucket(bucket))
+	exportPassphrase := "export passphrase"
+
+	exported, err := wallet.ExportBucket(did, exportPassphrase)
+	require.NoError(t, err)
+
+	t.Run("DecryptExport", func(t *testing.T) {
+		imported, envelope, err := wallet.DecryptExport(exported, exportPassphrase)
+		require.NoError(t, err)
+		assert.Equal(t, did, envelope.Did)
+		assert.Equal(t, bucket.Token, imported.Token)
+		assert.Equal(t, wallet.StateCreated, imported.State)
+		assert.Equal(t, bucket.IssuanceKey.KeyID(), imported.IssuanceKey.KeyID())
+	})
+	t.Run("InvalidPassphrase", func(t *testing.T) {
+		_, _, err := wallet.DecryptExport(exported, "another passphrase")
+		assert.ErrorIs(t, err, wallet.ErrInvalidExportPassphrase)
+		assert.NotErrorIs(t, err, wallet.ErrInvalidPassphrase)
+	})
+	t.Run("WeakPassphrase", func(t *testing.T) {
+		_, err := wallet.ExportBucket(did, "short")
+		assert.ErrorIs(t, err, wallet.ErrWeakPassphrase)
+	})
+	t.Run("Tampered", func(t *testing.T) {
+		message := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(exported, &message))
+		message["ciphertext"] = "AAAA" + message["ciphertext"].(string)[4:]
+		tampered, _ := json.Marshal(message)
+		_, _, err := wallet.DecryptExport(tampered, exportPassphrase)
+		assert.Error(t, err)
+
+		_, _, err = wallet.DecryptExport([]byte("not an export"), exportPassphrase)
+		assert.ErrorIs(t, err, wallet.ErrInvalidExport)
+	})
+	t.Run("Count", func(t *testing.T) {
+		message := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(exported, &message))
+		protected, err := base64.RawURLEncoding.DecodeString(message["protected"].(string))
+		require.NoError(t, err)
+		headers := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(protected, &headers))
+		assert.Equal(t, "PBES2-HS512+A256KW", headers["alg"])
+		assert.Equal(t, float64(210000), headers["p2c"])
+
+		// an export with a huge count is refused before the key is derived
+		headers["p2c"] = 1 << 40
+		protected, _ = json.Marshal(headers)
+		message["protected"] = base64.RawURLEncoding.EncodeToString(protected)
+		tampered, _ := json.Marshal(message)
+		_, _, err = wallet.DecryptExport(tampered, exportPassphrase)
+		assert.ErrorIs(t, err, wallet.ErrInvalidExport)
+		assert.ErrorContains(t, err, "exceeds the maximum")
+
+		require.NoError(t, json.Unmarshal(exported, &message))
+		message["header"] = map[string]interface{}{"alg": "PBES2-HS512+A256KW", "p2c": wallet.MaxExportCount + 1}
+		tampered, _ = json.Marshal(message)
+		_, _, err = wallet.DecryptExport(tampered, exportPassphrase)
+		assert.ErrorIs(t, err, wallet.ErrInvalidExport)
+	})
+	t.Run("ImportBucket", func(t *testing.T) {
+		imported, _, err := wallet.DecryptExport(exported, exportPassphrase)
+		require.NoError(t, err)
+		_, err = wallet.ImportBucket(imported, false)
+		assert.ErrorIs(t, err, wallet.ErrBucketExists)
+		replaced, err := wallet.ImportBucket(imported, true)
+		assert.NoError(t, err)
+		assert.True(t, replaced)
+		_, err = wallet.GetTrashedBucket(did)
+		assert.NoError(t, err)
+		_, err = wallet.PurgeTrash(false)
+		require.NoError(t, err)
+
+		require.NoError(t, wallet.RemoveBucket(did, true))
+		replaced, err = wallet.ImportBucket(imported, true)
+		assert.NoError(t, err)
+		assert.False(t, replaced)
+		_, err = wallet.GetBucketByDid(did)
+		assert.NoError(t, err)
+	})
+}
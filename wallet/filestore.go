@@ -23,6 +23,13 @@ type DidBucket struct {
 	AdminSigningKey     jwk.Key                `json:"sigKey,omitempty"`
 	Document            map[string]interface{} `json:"doc,omitempty"`
 	Token               string                 `json:"token,omitempty"`
+	AccessToken         string                 `json:"accessToken,omitempty"`
+	AccessTokenScope    string                 `json:"accessTokenScope,omitempty"`
+	AccessTokenExpiry   time.Time              `json:"accessTokenExp,omitempty"`
+	State               string                 `json:"state,omitempty"`
+	StateHistory        []StateChange          `json:"stateHistory,omitempty"`
+	VerificationKeys    []VerificationKey      `json:"keys,omitempty"`
+	KeyHistory          []VerificationKey      `json:"keyHistory,omitempty"`
 }
 
 type rawDidBucket struct {
@@ -34,18 +41,13 @@ type rawDidBucket struct {
 	AdminSigningKey     json.RawMessage `json:"sigKey,omitempty"`
 	Document            json.RawMessage `json:"doc,omitempty"`
 	Token               string          `json:"token,omitempty"`
-}
-
-var dbStore *MKVStore
-
-func init() {
-	var (
-		err error
-	)
-	dbStore, err = NewFileKVStore()
-	if err != nil {
-		panic(err)
-	}
+	AccessToken         string          `json:"accessToken,omitempty"`
+	AccessTokenScope    string          `json:"accessTokenScope,omitempty"`
+	AccessTokenExpiry   string          `json:"accessTokenExp,omitempty"`
+	State               string          `json:"state,omitempty"`
+	StateHistory        json.RawMessage `json:"stateHistory,omitempty"`
+	VerificationKeys    json.RawMessage `json:"keys,omitempty"`
+	KeyHistory          json.RawMessage `json:"keyHistory,omitempty"`
 }
 
 // MemoryStore token storage based on buntdb(https://github.com/tidwall/buntdb)
@@ -62,9 +64,9 @@ func NewMemoryKVStore() (*MKVStore, error) {
 	return &MKVStore{db}, nil
 }
 
-// NewMemoryKVStore create a store instance based on a file
-func NewFileKVStore() (*MKVStore, error) {
-	db, err := buntdb.Open("walletdata.db")
+// NewFileKVStore create a store instance based on a file
+func NewFileKVStore(path string) (*MKVStore, error) {
+	db, err := buntdb.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -79,16 +81,20 @@ func (m *MKVStore) Set(key string, value string, expires time.Duration) error {
 	if strings.TrimSpace(key) == "" {
 		return errors.New("key is empty")
 	}
-	m.DB.Update(func(tx *buntdb.Tx) error {
+	return m.DB.Update(func(tx *buntdb.Tx) error {
 		if expires > 0 {
 			// add 5 seconds for processing time
 			expires += time.Second * 5
 			expiresOption = &buntdb.SetOptions{Expires: true, TTL: expires}
 		}
-		tx.Set(key, value, expiresOption)
-		return nil
+		_, _, err := tx.Set(key, value, expiresOption)
+		return err
 	})
-	return nil
+}
+
+// Put persist the value with key without expiration
+func (m *MKVStore) Put(key string, value string) error {
+	return m.Set(key, value, -1)
 }
 
 func (m *MKVStore) Get(key string) (string, error) {
@@ -96,7 +102,7 @@ func (m *MKVStore) Get(key string) (string, error) {
 		value string
 	)
 	if strings.TrimSpace(key) == "" {
-		return "", buntdb.ErrNotFound
+		return "", ErrNotFound
 	}
 	err := m.DB.View(func(tx *buntdb.Tx) error {
 		val, err := tx.Get(key)
@@ -106,6 +112,9 @@ func (m *MKVStore) Get(key string) (string, error) {
 		value = val
 		return nil
 	})
+	if err == buntdb.ErrNotFound {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		return "", err
 	}
@@ -115,15 +124,23 @@ func (m *MKVStore) Get(key string) (string, error) {
 // remove key
 func (m *MKVStore) Remove(key string) error {
 	if strings.TrimSpace(key) == "" {
-		return buntdb.ErrNotFound
+		return ErrNotFound
 	}
 	err := m.DB.Update(func(tx *buntdb.Tx) error {
 		_, err := tx.Delete(key)
 		return err
 	})
+	if err == buntdb.ErrNotFound {
+		return ErrNotFound
+	}
 	return err
 }
 
+// Delete removes the value with key
+func (m *MKVStore) Delete(key string) error {
+	return m.Remove(key)
+}
+
 func (m *MKVStore) GetAllKeys() ([]string, error) {
 	var allKeys []string
 	err := m.DB.View(func(tx *buntdb.Tx) error {
@@ -139,31 +156,56 @@ func (m *MKVStore) GetAllKeys() ([]string, error) {
 	return allKeys, nil
 }
 
+// List returns all the keys in the store
+func (m *MKVStore) List() ([]string, error) {
+	return m.GetAllKeys()
+}
+
 func StoreBucket(bucket DidBucket) error {
+	if dbStore == nil {
+		return ErrNotOpen
+	}
 	bucketBytes, err := bucket.MarshalJSON()
 	if err != nil {
 		return err
 	}
-	return dbStore.Set(bucket.Did, string(bucketBytes), -1)
+	return encryptValue(bucket.Did, bucketBytes)
 }
 
 func GetBucketByDid(didSubject string) (DidBucket, error) {
-	bucketString, err := dbStore.Get(didSubject)
+	if dbStore == nil {
+		return DidBucket{}, ErrNotOpen
+	}
+	bucketBytes, err := decryptValue(didSubject)
 	if err != nil {
 		return DidBucket{}, err
 	}
 	bucket := DidBucket{}
-	if err = json.Unmarshal([]byte(bucketString), &bucket); err != nil {
+	if err = json.Unmarshal(bucketBytes, &bucket); err != nil {
 		return DidBucket{}, err
 	}
 	if bucket.Did != didSubject {
-		return bucket, errors.New("not found")
+		return bucket, ErrNotFound
 	}
 	return bucket, nil
 }
 
+// GetAllKeys returns the dids of the buckets in the wallet
 func GetAllKeys() ([]string, error) {
-	return dbStore.GetAllKeys()
+	if dbStore == nil {
+		return nil, ErrNotOpen
+	}
+	allKeys, err := dbStore.List()
+	if err != nil {
+		return nil, err
+	}
+	dids := []string{}
+	for _, key := range allKeys {
+		if !isReservedKey(key) {
+			dids = append(dids, key)
+		}
+	}
+	return dids, nil
 }
 
 func (bucket *DidBucket) MarshalJSON() ([]byte, error) {
@@ -178,6 +220,19 @@ func (bucket *DidBucket) MarshalJSON() ([]byte, error) {
 				rawBucket.Did = element
 			case "Token":
 				rawBucket.Token = element
+			case "AccessToken":
+				rawBucket.AccessToken = element
+			case "AccessTokenScope":
+				rawBucket.AccessTokenScope = element
+			case "State":
+				rawBucket.State = element
+			}
+		case time.Time:
+			if !element.IsZero() {
+				switch elements.Type().Field(i).Name {
+				case "AccessTokenExpiry":
+					rawBucket.AccessTokenExpiry = element.UTC().Format(time.RFC3339)
+				}
 			}
 		case jwk.Key:
 			if element != nil {
@@ -201,6 +256,22 @@ func (bucket *DidBucket) MarshalJSON() ([]byte, error) {
 					rawBucket.Document, _ = json.Marshal(element)
 				}
 			}
+		case []StateChange:
+			if len(element) > 0 {
+				switch elements.Type().Field(i).Name {
+				case "StateHistory":
+					rawBucket.StateHistory, _ = json.Marshal(element)
+				}
+			}
+		case []VerificationKey:
+			if len(element) > 0 {
+				switch elements.Type().Field(i).Name {
+				case "VerificationKeys":
+					rawBucket.VerificationKeys, _ = json.Marshal(element)
+				case "KeyHistory":
+					rawBucket.KeyHistory, _ = json.Marshal(element)
+				}
+			}
 		}
 	}
 	return json.Marshal(rawBucket)
@@ -223,6 +294,18 @@ func (bucket *DidBucket) UnmarshalJSON(data []byte) error {
 				bucket.Did = element
 			case "Token":
 				bucket.Token = element
+			case "AccessToken":
+				bucket.AccessToken = element
+			case "AccessTokenScope":
+				bucket.AccessTokenScope = element
+			case "State":
+				bucket.State = element
+			case "AccessTokenExpiry":
+				if element != "" {
+					if bucket.AccessTokenExpiry, err = time.Parse(time.RFC3339, element); err != nil {
+						return err
+					}
+				}
 			}
 		case json.RawMessage:
 			if element != nil {
@@ -239,6 +322,12 @@ func (bucket *DidBucket) UnmarshalJSON(data []byte) error {
 					bucket.AdminSigningKey, err = jwk.ParseKey(element)
 				case "Document":
 					err = json.Unmarshal(element, &bucket.Document)
+				case "StateHistory":
+					err = json.Unmarshal(element, &bucket.StateHistory)
+				case "VerificationKeys":
+					err = json.Unmarshal(element, &bucket.VerificationKeys)
+				case "KeyHistory":
+					err = json.Unmarshal(element, &bucket.KeyHistory)
 				}
 				if err != nil {
 					return err
@@ -246,5 +335,8 @@ func (bucket *DidBucket) UnmarshalJSON(data []byte) error {
 			}
 		}
 	}
+	if bucket.State == "" && bucket.Did != "" {
+		bucket.State = legacyState(bucket)
+	}
 	return nil
 }
@@ -20,6 +20,18 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+const testPassphrase string = "correct horse battery staple"
+
+func TestMain(m *testing.M) {
+	if err := wallet.Open(wallet.BackendMemory, ""); err != nil {
+		panic(err)
+	}
+	if err := wallet.Unlock(testPassphrase); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
 func TestBucket(t *testing.T) {
 	t.Run("VerifyNilValues", func(t *testing.T) {
 		var (
@@ -0,0 +1,135 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Roles of the keys of the bucket, the added verification keys have no role
+const (
+	KeyRoleIssuance     string = "issuance"
+	KeyRolePresentation string = "presentation"
+)
+
+// VerificationKey is a key added to the did document with its verification relationships, a retired key
+// in the key history is the public key valid till ValidUntil
+type VerificationKey struct {
+	Key        jwk.Key   `json:"key"`
+	Role       string    `json:"role,omitempty"`
+	Purposes   []string  `json:"purposes"`
+	ValidFrom  time.Time `json:"validFrom"`
+	ValidUntil time.Time `json:"validUntil,omitempty"`
+}
+
+type rawVerificationKey struct {
+	Key        json.RawMessage `json:"key"`
+	Role       string          `json:"role,omitempty"`
+	Purposes   []string        `json:"purposes"`
+	ValidFrom  time.Time       `json:"validFrom"`
+	ValidUntil *time.Time      `json:"validUntil,omitempty"`
+}
+
+func (key VerificationKey) MarshalJSON() ([]byte, error) {
+	var (
+		err error
+	)
+	rawKey := rawVerificationKey{Role: key.Role, Purposes: key.Purposes, ValidFrom: key.ValidFrom}
+	if key.Key != nil {
+		if rawKey.Key, err = json.Marshal(key.Key); err != nil {
+			return nil, err
+		}
+	}
+	if !key.ValidUntil.IsZero() {
+		rawKey.ValidUntil = &key.ValidUntil
+	}
+	return json.Marshal(rawKey)
+}
+
+func (key *VerificationKey) UnmarshalJSON(data []byte) error {
+	var (
+		err error
+	)
+	rawKey := rawVerificationKey{}
+	if err = json.Unmarshal(data, &rawKey); err != nil {
+		return err
+	}
+	if rawKey.Key != nil {
+		if key.Key, err = jwk.ParseKey(rawKey.Key); err != nil {
+			return err
+		}
+	}
+	key.Role = rawKey.Role
+	key.Purposes = rawKey.Purposes
+	key.ValidFrom = rawKey.ValidFrom
+	if rawKey.ValidUntil != nil {
+		key.ValidUntil = *rawKey.ValidUntil
+	}
+	return nil
+}
+
+// VerificationKeyById returns the added verification key with the key id
+func (bucket *DidBucket) VerificationKeyById(keyId string) (VerificationKey, bool) {
+	for _, key := range bucket.VerificationKeys {
+		if key.Key != nil && key.Key.KeyID() == keyId {
+			return key, true
+		}
+	}
+	return VerificationKey{}, false
+}
+
+// RetiredKeyById returns the retired key with the key id from the key history, the latest retirement first
+func (bucket *DidBucket) RetiredKeyById(keyId string) (VerificationKey, bool) {
+	for i := len(bucket.KeyHistory) - 1; i >= 0; i-- {
+		if key := bucket.KeyHistory[i].Key; key != nil && key.KeyID() == keyId {
+			return bucket.KeyHistory[i], true
+		}
+	}
+	return VerificationKey{}, false
+}
+
+// RetireKey moves the public key of the key to the key history, the key is valid till now. An added
+// verification key is removed from the verification keys, the key of a role is replaced by the caller.
+func (bucket *DidBucket) RetireKey(key jwk.Key, role string, purposes []string) (VerificationKey, error) {
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return VerificationKey{}, err
+	}
+	retiredKey := VerificationKey{
+		Key:        publicKey,
+		Role:       role,
+		Purposes:   purposes,
+		ValidFrom:  bucket.KeyValidFrom(key.KeyID(), role),
+		ValidUntil: time.Now().UTC(),
+	}
+	verificationKeys := []VerificationKey{}
+	for _, verificationKey := range bucket.VerificationKeys {
+		if verificationKey.Key == nil || verificationKey.Key.KeyID() != key.KeyID() {
+			verificationKeys = append(verificationKeys, verificationKey)
+		}
+	}
+	bucket.VerificationKeys = verificationKeys
+	bucket.KeyHistory = append(bucket.KeyHistory, retiredKey)
+	return retiredKey, nil
+}
+
+// KeyValidFrom returns the start of the validity of the key, that is when the key was added, when its predecessor
+// of the role was retired or when the did was created
+func (bucket *DidBucket) KeyValidFrom(keyId string, role string) time.Time {
+	if verificationKey, found := bucket.VerificationKeyById(keyId); found {
+		return verificationKey.ValidFrom
+	}
+	for i := len(bucket.KeyHistory) - 1; i >= 0; i-- {
+		if role != "" && bucket.KeyHistory[i].Role == role {
+			return bucket.KeyHistory[i].ValidUntil
+		}
+	}
+	if len(bucket.StateHistory) > 0 {
+		return bucket.StateHistory[0].ChangedAt
+	}
+	return time.Time{}
+}
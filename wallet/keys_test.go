@@ -0,0 +1,69 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationKeys(t *testing.T) {
+	t.Run("StoreKeys", func(t *testing.T) {
+		did := "did:ebsi:zsV4Pmr4LZ8cAE9uXsGBPBz"
+		jwkKey, err := generateSecp256r1AsJwk(did)
+		require.NoError(t, err)
+
+		bucket := wallet.DidBucket{Did: did, State: wallet.StateUpdated}
+		bucket.VerificationKeys = append(bucket.VerificationKeys, wallet.VerificationKey{
+			Key:       jwkKey,
+			Purposes:  []string{"authentication", "capabilityInvocation"},
+			ValidFrom: time.Now().UTC().Truncate(time.Second),
+		})
+		require.NoError(t, wallet.StoreBucket(bucket))
+
+		storedBucket, err := wallet.GetBucketByDid(did)
+		require.NoError(t, err)
+		storedKey, found := storedBucket.VerificationKeyById(jwkKey.KeyID())
+		assert.True(t, found)
+		assert.Equal(t, bucket.VerificationKeys[0].Purposes, storedKey.Purposes)
+		assert.True(t, bucket.VerificationKeys[0].ValidFrom.Equal(storedKey.ValidFrom))
+		assert.NotNil(t, storedKey.Key)
+
+		_, found = storedBucket.VerificationKeyById(did + "#unknown")
+		assert.False(t, found)
+	})
+	t.Run("RetireKey", func(t *testing.T) {
+		did := "did:web:example.org"
+		createdAt := time.Now().UTC().Add(-time.Hour)
+		bucket := wallet.DidBucket{Did: did, StateHistory: []wallet.StateChange{{State: wallet.StateCreated, ChangedAt: createdAt}}}
+		bucket.IssuanceKey, _ = generateSecp256r1AsJwk(did)
+
+		retiredKey, err := bucket.RetireKey(bucket.IssuanceKey, wallet.KeyRoleIssuance, []string{"assertionMethod"})
+		require.NoError(t, err)
+		assert.True(t, createdAt.Equal(retiredKey.ValidFrom))
+		assert.False(t, retiredKey.ValidUntil.IsZero())
+		_, isPrivate := retiredKey.Key.(interface{ D() []byte })
+		assert.False(t, isPrivate)
+
+		bucket.IssuanceKey, _ = generateSecp256r1AsJwk(did)
+		assert.True(t, retiredKey.ValidUntil.Equal(bucket.KeyValidFrom(bucket.IssuanceKey.KeyID(), wallet.KeyRoleIssuance)))
+
+		require.NoError(t, wallet.StoreBucket(bucket))
+		storedBucket, err := wallet.GetBucketByDid(did)
+		require.NoError(t, err)
+		require.Len(t, storedBucket.KeyHistory, 1)
+		assert.Equal(t, wallet.KeyRoleIssuance, storedBucket.KeyHistory[0].Role)
+		assert.True(t, retiredKey.ValidUntil.Equal(storedBucket.KeyHistory[0].ValidUntil))
+
+		historyKey, found := storedBucket.RetiredKeyById(retiredKey.Key.KeyID())
+		assert.True(t, found)
+		assert.True(t, retiredKey.ValidFrom.Equal(historyKey.ValidFrom))
+		_, found = storedBucket.RetiredKeyById(bucket.IssuanceKey.KeyID())
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// States of the lifecycle of a did
+const (
+	StateCreated     string = "created"
+	StateOnboarded   string = "onboarded"
+	StateRegistered  string = "registered"
+	StateUpdated     string = "updated"
+	StateDeactivated string = "deactivated"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrDidDeactivated    = errors.New("the did is deactivated")
+)
+
+// stateTransitions are the states that can follow a state, onboarding is repeated to renew the token. A did
+// that is not registered is deactivated in the wallet only.
+var stateTransitions = map[string][]string{
+	"":               {StateCreated},
+	StateCreated:     {StateOnboarded, StateDeactivated},
+	StateOnboarded:   {StateOnboarded, StateRegistered, StateDeactivated},
+	StateRegistered:  {StateUpdated, StateDeactivated},
+	StateUpdated:     {StateUpdated, StateDeactivated},
+	StateDeactivated: {},
+}
+
+// StateChange is a transition in the lifecycle of the did with the result of the remote api
+type StateChange struct {
+	State           string    `json:"state"`
+	ChangedAt       time.Time `json:"changedAt"`
+	TransactionHash string    `json:"transactionHash,omitempty"`
+	Response        string    `json:"response,omitempty"`
+}
+
+// CanChangeState returns an error when the did can't move from the state to the next state
+func CanChangeState(from string, to string) error {
+	for _, state := range stateTransitions[from] {
+		if state == to {
+			return nil
+		}
+	}
+	if from == "" {
+		return fmt.Errorf("%w: a new did must be %s", ErrInvalidTransition, StateCreated)
+	}
+	return fmt.Errorf("%w: the did is %s and can't be %s", ErrInvalidTransition, from, to)
+}
+
+// ChangeState moves the did to the state of the change and adds the change to the history
+func (bucket *DidBucket) ChangeState(change StateChange) error {
+	if err := CanChangeState(bucket.State, change.State); err != nil {
+		return err
+	}
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now().UTC()
+	}
+	bucket.State = change.State
+	bucket.StateHistory = append(bucket.StateHistory, change)
+	return nil
+}
+
+// CanSign returns an error when the keys of the did must not be used for signing, the keys of a deactivated
+// did are only used when explicitly allowed
+func (bucket *DidBucket) CanSign(allowDeactivated bool) error {
+	if bucket.State == StateDeactivated && !allowDeactivated {
+		return fmt.Errorf("%w: %s", ErrDidDeactivated, bucket.Did)
+	}
+	return nil
+}
+
+// LastStateChange returns the last change of the state, empty for a did without history
+func (bucket *DidBucket) LastStateChange() StateChange {
+	if len(bucket.StateHistory) == 0 {
+		return StateChange{State: bucket.State}
+	}
+	return bucket.StateHistory[len(bucket.StateHistory)-1]
+}
+
+// legacyState derives the state of a bucket stored before the lifecycle was recorded
+func legacyState(bucket *DidBucket) string {
+	switch {
+	case bucket.AdminTransactionKey != nil:
+		return StateRegistered
+	case strings.TrimSpace(bucket.Token) != "":
+		return StateOnboarded
+	default:
+		return StateCreated
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	t.Run("ValidTransitions", func(t *testing.T) {
+		bucket := wallet.DidBucket{Did: "did:ebsi:zfEmvX5twhXjQJiCWsukvQA"}
+		for _, state := range []string{wallet.StateCreated, wallet.StateOnboarded, wallet.StateOnboarded, wallet.StateRegistered, wallet.StateUpdated, wallet.StateDeactivated} {
+			assert.NoError(t, bucket.ChangeState(wallet.StateChange{State: state}))
+		}
+		assert.Equal(t, wallet.StateDeactivated, bucket.State)
+		assert.Len(t, bucket.StateHistory, 6)
+		assert.False(t, bucket.LastStateChange().ChangedAt.IsZero())
+	})
+	t.Run("InvalidTransitions", func(t *testing.T) {
+		for from, to := range map[string]string{
+			"":                      wallet.StateOnboarded,
+			wallet.StateCreated:     wallet.StateRegistered,
+			wallet.StateRegistered:  wallet.StateOnboarded,
+			wallet.StateDeactivated: wallet.StateUpdated,
+		} {
+			bucket := wallet.DidBucket{State: from}
+			assert.ErrorIs(t, bucket.ChangeState(wallet.StateChange{State: to}), wallet.ErrInvalidTransition)
+			assert.Equal(t, from, bucket.State)
+		}
+	})
+	t.Run("StoreState", func(t *testing.T) {
+		bucket := wallet.DidBucket{Did: "did:ebsi:zvHWX359A3CvfJnCYaAiAde"}
+		assert.NoError(t, bucket.ChangeState(wallet.StateChange{State: wallet.StateCreated}))
+		assert.NoError(t, bucket.ChangeState(wallet.StateChange{State: wallet.StateOnboarded}))
+		assert.NoError(t, wallet.StoreBucket(bucket))
+
+		storedBucket, err := wallet.GetBucketByDid(bucket.Did)
+		assert.NoError(t, err)
+		assert.Equal(t, wallet.StateOnboarded, storedBucket.State)
+		assert.Len(t, storedBucket.StateHistory, 2)
+		assert.True(t, bucket.LastStateChange().ChangedAt.Equal(storedBucket.LastStateChange().ChangedAt))
+	})
+	t.Run("CanSign", func(t *testing.T) {
+		bucket := wallet.DidBucket{Did: "did:web:example.org", State: wallet.StateCreated}
+		assert.NoError(t, bucket.CanSign(false))
+		assert.NoError(t, bucket.ChangeState(wallet.StateChange{State: wallet.StateDeactivated}))
+		assert.ErrorIs(t, bucket.CanSign(false), wallet.ErrDidDeactivated)
+		assert.NoError(t, bucket.CanSign(true))
+	})
+	t.Run("LegacyState", func(t *testing.T) {
+		bucket := wallet.DidBucket{}
+		assert.NoError(t, bucket.UnmarshalJSON([]byte(`{"did":"did:ebsi:zfEmvX5twhXjQJiCWsukvQA","token":"eyJ0eXAiOiJKV1QifQ"}`)))
+		assert.Equal(t, wallet.StateOnboarded, bucket.State)
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultWalletName string = "default"
+	appName           string = "essif"
+	currentWalletFile string = "current-wallet"
+	walletExtension   string = ".db"
+)
+
+var validWalletName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// DataDir returns the directory of the wallets, following the XDG base directory specification
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// ConfigDir returns the directory of the configuration, following the XDG base directory specification
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+func xdgDir(envName string, homeRelative string) (string, error) {
+	if dir := os.Getenv(envName); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, homeRelative, appName), nil
+}
+
+// IsWalletName returns true when the wallet is referred to by name instead of by path
+func IsWalletName(wallet string) bool {
+	return validWalletName.MatchString(wallet) && filepath.Ext(wallet) == ""
+}
+
+// Location returns the file or directory of the wallet for the backend, the wallet is a name or a path
+func Location(backend string, wallet string) (string, error) {
+	if backend == BackendMemory {
+		return "", nil
+	}
+	if strings.TrimSpace(wallet) == "" {
+		return "", errors.New("wallet is empty")
+	}
+	if !IsWalletName(wallet) {
+		return wallet, nil
+	}
+	dataDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	walletsDir := filepath.Join(dataDir, "wallets")
+	if err = os.MkdirAll(walletsDir, 0700); err != nil {
+		return "", err
+	}
+	switch backend {
+	case BackendDirectory:
+		return filepath.Join(walletsDir, wallet), nil
+	default:
+		return filepath.Join(walletsDir, wallet+walletExtension), nil
+	}
+}
+
+// CurrentName returns the name of the wallet selected with UseName, or the default wallet
+func CurrentName() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	name, err := os.ReadFile(filepath.Join(configDir, currentWalletFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultWalletName, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(name)) == "" {
+		return DefaultWalletName, nil
+	}
+	return strings.TrimSpace(string(name)), nil
+}
+
+// UseName selects the named wallet as the current wallet
+func UseName(name string) error {
+	if !IsWalletName(name) {
+		return fmt.Errorf("invalid wallet name: %s", name)
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(configDir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, currentWalletFile), []byte(name+"\n"), 0600)
+}
+
+// Names returns the names of the wallets in the data directory
+func Names() ([]string, error) {
+	dataDir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dataDir, "wallets"))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	unique := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			if filepath.Ext(name) != walletExtension {
+				continue
+			}
+			name = strings.TrimSuffix(name, walletExtension)
+		}
+		if IsWalletName(name) {
+			unique[name] = true
+		}
+	}
+	names := []string{}
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
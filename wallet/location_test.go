@@ -0,0 +1,55 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocation(t *testing.T) {
+	dataHome, configHome := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	t.Run("DefaultLocation", func(t *testing.T) {
+		location, err := wallet.Location(wallet.BackendBuntDB, wallet.DefaultWalletName)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dataHome, "essif", "wallets", "default.db"), location)
+
+		location, err = wallet.Location(wallet.BackendDirectory, "acme")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dataHome, "essif", "wallets", "acme"), location)
+	})
+	t.Run("PathLocation", func(t *testing.T) {
+		location, err := wallet.Location(wallet.BackendBuntDB, "./walletdata.db")
+		assert.NoError(t, err)
+		assert.Equal(t, "./walletdata.db", location)
+	})
+	t.Run("UseName", func(t *testing.T) {
+		currentName, err := wallet.CurrentName()
+		assert.NoError(t, err)
+		assert.Equal(t, wallet.DefaultWalletName, currentName)
+
+		assert.NoError(t, wallet.UseName("acme"))
+		currentName, err = wallet.CurrentName()
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", currentName)
+
+		assert.Error(t, wallet.UseName("../acme"))
+	})
+	t.Run("Names", func(t *testing.T) {
+		walletsDir := filepath.Join(dataHome, "essif", "wallets")
+		assert.NoError(t, os.WriteFile(filepath.Join(walletsDir, "acme.db"), []byte{}, 0600))
+		assert.NoError(t, os.MkdirAll(filepath.Join(walletsDir, "contoso"), 0700))
+
+		names, err := wallet.Names()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"acme", "contoso"}, names)
+	})
+}
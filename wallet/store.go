@@ -0,0 +1,79 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	BackendBuntDB    string = "buntdb"
+	BackendDirectory string = "dir"
+	BackendMemory    string = "memory"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrNotOpen  = errors.New("wallet is not opened")
+)
+
+// Store is the storage backend of the wallet, the values are the encrypted buckets
+type Store interface {
+	// Get returns the value with key or ErrNotFound
+	Get(key string) (string, error)
+	// Put persists the value with key
+	Put(key string, value string) error
+	// Delete removes the value with key
+	Delete(key string) error
+	// List returns all the keys in the store
+	List() ([]string, error)
+	// Close releases the resources of the store
+	Close() error
+}
+
+var dbStore Store
+
+// NewStore creates a store instance of the backend, the location is the file or directory of the store
+func NewStore(backend string, location string) (Store, error) {
+	switch backend {
+	case BackendBuntDB:
+		return NewFileKVStore(location)
+	case BackendDirectory:
+		return NewDirStore(location)
+	case BackendMemory:
+		return NewMemoryKVStore()
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", backend)
+	}
+}
+
+// Open opens the wallet with the backend, an opened wallet is closed first
+func Open(backend string, location string) error {
+	store, err := NewStore(backend, location)
+	if err != nil {
+		return err
+	}
+	return Use(store)
+}
+
+// Use opens the wallet with the store, an opened wallet is closed first
+func Use(store Store) error {
+	if err := Close(); err != nil {
+		return err
+	}
+	dbStore = store
+	return nil
+}
+
+// Close locks and closes the wallet
+func Close() error {
+	Lock()
+	if dbStore == nil {
+		return nil
+	}
+	err := dbStore.Close()
+	dbStore = nil
+	return err
+}
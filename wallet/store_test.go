@@ -0,0 +1,52 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	backends := map[string]string{
+		wallet.BackendBuntDB:    filepath.Join(t.TempDir(), "walletdata.db"),
+		wallet.BackendDirectory: filepath.Join(t.TempDir(), "walletdata"),
+		wallet.BackendMemory:    "",
+	}
+	for backend, location := range backends {
+		t.Run(backend, func(t *testing.T) {
+			var (
+				expectedKey string = "did:example:123"
+			)
+			store, err := wallet.NewStore(backend, location)
+			assert.NoError(t, err)
+			defer store.Close()
+
+			_, err = store.Get(expectedKey)
+			assert.ErrorIs(t, err, wallet.ErrNotFound)
+
+			assert.NoError(t, store.Put(expectedKey, `{"did":"did:example:123"}`))
+			assert.NoError(t, store.Put("did:example:456", `{"did":"did:example:456"}`))
+			actualValue, err := store.Get(expectedKey)
+			assert.NoError(t, err)
+			assert.Equal(t, `{"did":"did:example:123"}`, actualValue)
+
+			actualKeys, err := store.List()
+			assert.NoError(t, err)
+			assert.Equal(t, []string{expectedKey, "did:example:456"}, actualKeys)
+
+			assert.NoError(t, store.Delete(expectedKey))
+			assert.ErrorIs(t, store.Delete(expectedKey), wallet.ErrNotFound)
+			_, err = store.Get(expectedKey)
+			assert.ErrorIs(t, err, wallet.ErrNotFound)
+		})
+	}
+	t.Run("UnsupportedBackend", func(t *testing.T) {
+		_, err := wallet.NewStore("unknown", "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// trashPrefix is the reserved prefix of the keys of the removed buckets
+	trashPrefix string = "essif:trash:"
+	// TrashGracePeriod is the period a removed bucket can be restored
+	TrashGracePeriod time.Duration = 30 * 24 * time.Hour
+)
+
+var ErrBucketExists = errors.New("the did is already in the wallet")
+
+// TrashedBucket is a removed bucket that can be restored until the grace period has passed
+type TrashedBucket struct {
+	Did       string          `json:"did"`
+	State     string          `json:"state"`
+	RemovedAt time.Time       `json:"removedAt"`
+	Bucket    json.RawMessage `json:"bucket"`
+}
+
+// ExpiresAt returns the time the bucket is purged from the trash
+func (trashed TrashedBucket) ExpiresAt() time.Time {
+	return trashed.RemovedAt.Add(TrashGracePeriod)
+}
+
+// isReservedKey returns true for the keys of the store that aren't did buckets
+func isReservedKey(key string) bool {
+	return key == headerKey || key == credentialIndexKey || strings.HasPrefix(key, trashPrefix) || strings.HasPrefix(key, credentialPrefix)
+}
+
+// RemoveBucket removes the bucket of the did from the wallet, the bucket is moved to the trash unless permanent is set.
+// A permanent removal deletes the bucket of the did in the trash as well.
+func RemoveBucket(did string, permanent bool) error {
+	if dbStore == nil {
+		return ErrNotOpen
+	}
+	bucket, err := GetBucketByDid(did)
+	if err != nil {
+		return err
+	}
+	if permanent {
+		if err = dbStore.Delete(trashPrefix + did); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	} else {
+		bucketBytes, err := bucket.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		trashed := TrashedBucket{Did: did, State: bucket.State, RemovedAt: time.Now().UTC(), Bucket: bucketBytes}
+		trashedBytes, err := json.Marshal(trashed)
+		if err != nil {
+			return err
+		}
+		if err = encryptValue(trashPrefix+did, trashedBytes); err != nil {
+			return err
+		}
+	}
+	return dbStore.Delete(did)
+}
+
+// GetTrashedBucket returns the removed bucket of the did or ErrNotFound
+func GetTrashedBucket(did string) (TrashedBucket, error) {
+	if dbStore == nil {
+		return TrashedBucket{}, ErrNotOpen
+	}
+	trashedBytes, err := decryptValue(trashPrefix + did)
+	if err != nil {
+		return TrashedBucket{}, err
+	}
+	trashed := TrashedBucket{}
+	if err = json.Unmarshal(trashedBytes, &trashed); err != nil {
+		return TrashedBucket{}, err
+	}
+	return trashed, nil
+}
+
+// TrashedBuckets returns the removed buckets, ordered by the time of removal
+func TrashedBuckets() ([]TrashedBucket, error) {
+	if dbStore == nil {
+		return nil, ErrNotOpen
+	}
+	allKeys, err := dbStore.List()
+	if err != nil {
+		return nil, err
+	}
+	trash := []TrashedBucket{}
+	for _, key := range allKeys {
+		if !strings.HasPrefix(key, trashPrefix) {
+			continue
+		}
+		trashed, err := GetTrashedBucket(strings.TrimPrefix(key, trashPrefix))
+		if err != nil {
+			return nil, err
+		}
+		trash = append(trash, trashed)
+	}
+	sort.Slice(trash, func(i, j int) bool { return trash[i].RemovedAt.Before(trash[j].RemovedAt) })
+	return trash, nil
+}
+
+// RestoreBucket moves the removed bucket of the did back to the wallet, a did in the wallet isn't overwritten
+func RestoreBucket(did string) (DidBucket, error) {
+	trashed, err := GetTrashedBucket(did)
+	if err != nil {
+		return DidBucket{}, err
+	}
+	if _, err = dbStore.Get(did); err == nil {
+		return DidBucket{}, ErrBucketExists
+	}
+	bucket := DidBucket{}
+	if err = json.Unmarshal(trashed.Bucket, &bucket); err != nil {
+		return DidBucket{}, err
+	}
+	if err = StoreBucket(bucket); err != nil {
+		return DidBucket{}, err
+	}
+	return bucket, dbStore.Delete(trashPrefix + did)
+}
+
+// PurgeTrash deletes the removed buckets of which the grace period has passed, or all when expired is false.
+// It returns the dids of the deleted buckets.
+func PurgeTrash(expired bool) ([]string, error) {
+	trash, err := TrashedBuckets()
+	if err != nil {
+		return nil, err
+	}
+	purged := []string{}
+	for _, trashed := range trash {
+		if expired && time.Now().Before(trashed.ExpiresAt()) {
+			continue
+		}
+		if err = dbStore.Delete(trashPrefix + trashed.Did); err != nil {
+			return purged, err
+		}
+		purged = append(purged, trashed.Did)
+	}
+	return purged, nil
+}
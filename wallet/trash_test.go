@@ -0,0 +1,76 @@
+// Copyright 2023 The Go SSI Framework Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/gossif/admin/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	did := "did:ebsi:zrP7S6ZqEn1vWPDMdTyx5jW"
+	bucket := wallet.DidBucket{Did: did, State: wallet.StateCreated}
+	bucket.IssuanceKey, _ = generateSecp256r1AsJwk(did)
+	require.NoError(t, wallet.StoreBucket(bucket))
+
+	t.Run("RemoveBucket", func(t *testing.T) {
+		require.NoError(t, wallet.RemoveBucket(did, false))
+		_, err := wallet.GetBucketByDid(did)
+		assert.ErrorIs(t, err, wallet.ErrNotFound)
+		dids, err := wallet.GetAllKeys()
+		require.NoError(t, err)
+		assert.NotContains(t, dids, did)
+
+		trash, err := wallet.TrashedBuckets()
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.Equal(t, did, trash[0].Did)
+		assert.Equal(t, wallet.StateCreated, trash[0].State)
+	})
+	t.Run("PurgeExpired", func(t *testing.T) {
+		purged, err := wallet.PurgeTrash(true)
+		require.NoError(t, err)
+		assert.Empty(t, purged)
+	})
+	t.Run("RestoreBucket", func(t *testing.T) {
+		restored, err := wallet.RestoreBucket(did)
+		require.NoError(t, err)
+		assert.Equal(t, bucket.IssuanceKey.KeyID(), restored.IssuanceKey.KeyID())
+		_, err = wallet.GetBucketByDid(did)
+		assert.NoError(t, err)
+		_, err = wallet.GetTrashedBucket(did)
+		assert.ErrorIs(t, err, wallet.ErrNotFound)
+	})
+	t.Run("RestoreExisting", func(t *testing.T) {
+		require.NoError(t, wallet.RemoveBucket(did, false))
+		require.NoError(t, wallet.StoreBucket(bucket))
+		_, err := wallet.RestoreBucket(did)
+		assert.ErrorIs(t, err, wallet.ErrBucketExists)
+
+		purged, err := wallet.PurgeTrash(false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{did}, purged)
+	})
+	t.Run("RemovePermanent", func(t *testing.T) {
+		require.NoError(t, wallet.RemoveBucket(did, true))
+		_, err := wallet.GetTrashedBucket(did)
+		assert.ErrorIs(t, err, wallet.ErrNotFound)
+		assert.ErrorIs(t, wallet.RemoveBucket(did, true), wallet.ErrNotFound)
+	})
+	t.Run("RemovePermanentTrashed", func(t *testing.T) {
+		// the keys of a trashed bucket, f.e. left by an overwriting import, are removed with the did
+		require.NoError(t, wallet.StoreBucket(bucket))
+		_, err := wallet.ImportBucket(bucket, true)
+		require.NoError(t, err)
+		_, err = wallet.GetTrashedBucket(did)
+		require.NoError(t, err)
+
+		require.NoError(t, wallet.RemoveBucket(did, true))
+		_, err = wallet.GetTrashedBucket(did)
+		assert.ErrorIs(t, err, wallet.ErrNotFound)
+	})
+}